@@ -0,0 +1,115 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type benchLevel3 struct {
+	Name  string   `cfg:"name"`
+	Value int      `cfg:"value"`
+	Tags  []string `cfg:"tags"`
+}
+
+type benchLevel2 struct {
+	Name  string      `cfg:"name"`
+	Value int         `cfg:"value"`
+	Inner benchLevel3 `cfg:"inner"`
+}
+
+type benchLevel1 struct {
+	Name  string      `cfg:"name"`
+	Value int         `cfg:"value"`
+	Inner benchLevel2 `cfg:"inner"`
+}
+
+type benchConfig struct {
+	Name      string      `cfg:"name"`
+	Port      int         `cfg:"port"`
+	Debug     bool        `cfg:"debug"`
+	Resources []string    `cfg:"resources"`
+	Voice     benchLevel1 `cfg:"voice"`
+}
+
+// generateConfig returns a config with the given number of resources and
+// repeated nested blocks, each nested block is three levels deep
+func generateConfig(resources int, blocks int) []byte {
+	var b strings.Builder
+
+	b.WriteString("name: 'Benchmark',\nport: 7788,\ndebug: false, # comment\nresources: [\n")
+
+	for i := 0; i < resources; i++ {
+		fmt.Fprintf(&b, "  'resource-%d',\n", i)
+	}
+
+	b.WriteString("]\n")
+
+	for i := 0; i < blocks; i++ {
+		fmt.Fprintf(&b, `voice: {
+  name: 'level1-%d'
+  value: %d
+  inner: {
+    name: 'level2'
+    value: 2
+    inner: {
+      name: 'level3'
+      value: 3
+      tags: [a, b, c]
+    }
+  }
+}
+`, i, i)
+	}
+
+	return []byte(b.String())
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	cases := []struct {
+		name      string
+		resources int
+		blocks    int
+	}{
+		{"small", 10, 1},
+		{"resources", 5000, 1},
+		{"nested", 10, 1000},
+		{"large", 5000, 1000},
+	}
+
+	for _, c := range cases {
+		data := generateConfig(c.resources, c.blocks)
+
+		b.Run(c.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				var v benchConfig
+
+				err := Unmarshal(data, &v)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	var v benchConfig
+
+	err := Unmarshal(generateConfig(100, 1), &v)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Marshal(v)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
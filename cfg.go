@@ -1,7 +1,6 @@
 package cfg
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"reflect"
@@ -13,13 +12,18 @@ import (
 
 const tagName = "cfg"
 
-type decodeState struct {
-	line    int
-	scanner *bufio.Scanner
-}
+const envTagName = "env"
 
-func (d *decodeState) init(data []byte) {
-	d.scanner = bufio.NewScanner(bytes.NewReader(data))
+type decodeState struct {
+	limits Limits
+	// md collects the metadata of the decoding, nil when not needed
+	md *Metadata
+	// interpolate enables variables in values, resolved through lookup
+	interpolate bool
+	lookup      LookupFunc
+	// merge makes decoded arrays replace the slice, unless the field
+	// appends, like Merge does. Otherwise arrays append to the slice
+	merge bool
 }
 
 type field struct {
@@ -28,275 +32,202 @@ type field struct {
 	IsInner bool
 	Kind    reflect.Kind
 	Value   reflect.Value
+	// Append makes decoded arrays append to the slice instead of replacing it
+	Append bool
+	set    setter
 }
 
 func extractFields(rv reflect.Value) []field {
-	var fields []field
 	v := rv
 
 	if rv.Kind() == reflect.Ptr {
 		v = rv.Elem()
 	}
 
-	for i := 0; i < v.Type().NumField(); i++ {
-		fieldType := v.Type().Field(i)
-		fieldValue := v.Field(i)
-		fieldTag := fieldType.Tag.Get(tagName)
-		fieldKind := fieldValue.Type().Kind()
-
-		if len(fieldTag) == 0 {
-			fieldTag = fieldType.Name
-		}
+	plan := planOf(v.Type())
+	fields := make([]field, 0, len(plan.fields))
 
-		fieldTag = strings.TrimSpace(fieldTag)
-
-		fields = append(fields, field{
-			Tag:     fieldTag,
-			IsArray: fieldKind == reflect.Slice,
-			IsInner: fieldKind == reflect.Struct,
-			Kind:    fieldKind,
-			Value:   fieldValue,
-		})
+	for i := range plan.fields {
+		fields = append(fields, plan.fields[i].field(v))
 	}
 
 	return fields
 }
 
-func (d *decodeState) readNextValidLine() (string, error) {
-	for d.scanner.Scan() {
-		d.line++
+// decodeTarget returns the struct pointed by v
+func decodeTarget(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
 
-		line := d.scanner.Text()
+	if rv.Kind() != reflect.Ptr {
+		return rv, fmt.Errorf("decode target should be a pointer to a struct, got %s", reflect.TypeOf(v))
+	}
 
-		// comment line or empty line
-		if len(line) < 1 || line[0] == '#' {
-			continue
-		}
+	if rv.IsNil() {
+		return rv, fmt.Errorf("decode target is nil, %s", reflect.TypeOf(v))
+	}
 
-		// remove comment from end of line
-		commentPos := strings.Index(line, "#")
-		if commentPos >= 0 {
-			line = strings.TrimSpace(line[0:commentPos])
-		}
+	if rv.Elem().Type().Kind() != reflect.Struct {
+		return rv, fmt.Errorf("decode target should point to a struct, got %s", rv.Elem().Type())
+	}
+
+	return rv.Elem(), nil
+}
 
-		return line, nil
+func (d *decodeState) unmarshal(data []byte, v interface{}) error {
+	rv, err := decodeTarget(v)
+	if err != nil {
+		return err
+	}
+
+	root, err := parse(data, d.limits)
+	if err != nil {
+		return err
 	}
 
-	return "", errors.New(fmt.Sprintf("could not read line %d, end of file", d.line))
+	return d.object(root, rv, "")
 }
 
-func (d *decodeState) unmarshal(v interface{}) error {
-	rv := reflect.ValueOf(v)
+func (d *decodeState) object(n *Node, rv reflect.Value, prefix string) error {
+	plan := planOf(rv.Type())
 
-	if rv.Kind() != reflect.Ptr {
-		return fmt.Errorf("decode target should be a pointer to a struct, got %s", reflect.TypeOf(v))
+	for _, e := range n.Entries {
+		err := d.entry(e, rv, plan, prefix)
+		if err != nil {
+			return err
+		}
 	}
 
-	if rv.IsNil() {
-		return fmt.Errorf("decode target is nil, %s", reflect.TypeOf(v))
+	return nil
+}
+
+// entry decodes a key value pair into the fields of the struct rv
+func (d *decodeState) entry(e *Entry, rv reflect.Value, plan *structPlan, prefix string) error {
+	path := joinPath(prefix, e.Key)
+	fields := plan.byTag[e.Key]
+
+	if d.md != nil {
+		d.md.addKey(path, e.Pos, len(fields) > 0)
+
+		if len(fields) == 0 {
+			d.md.addPositions(e.Value, path)
+		}
 	}
 
-	if rv.Elem().Type().Kind() != reflect.Struct {
-		return fmt.Errorf("decode target should point to a struct, got %s", reflect.TypeOf(rv.Elem()))
+	for _, i := range fields {
+		err := d.value(e, plan.fields[i].field(rv), path)
+		if err != nil {
+			return err
+		}
 	}
 
-	fields := extractFields(rv)
+	return nil
+}
 
-	for d.scanner.Scan() {
-		d.line++
-		line := strings.TrimSpace(d.scanner.Text())
+func (d *decodeState) value(e *Entry, f field, path string) error {
+	n := e.Value
 
-		// remove comment line or empty line
-		if len(line) < 1 || line[0] == '#' {
-			continue
+	switch n.Kind {
+	case ArrayNode:
+		if !f.IsArray {
+			return errors.New(fmt.Sprintf("could not parse array into non-slice type on line %d", n.Pos.Line))
 		}
 
-		valueSeparatorPos := strings.Index(line, ":")
+		if d.md != nil {
+			action := MergeReplace
 
-		// we have a key value pair (this can be easy)
-		if valueSeparatorPos >= 0 {
-			matches := strings.SplitN(line, ":", 2)
+			if f.Append {
+				action = MergeAppend
+			}
 
-			// handle the case where key is the only thing on the line
-			if len(matches) < 2 || len(matches[1]) == 0 {
-				nextLine, err := d.readNextValidLine()
-				if err != nil {
-					return err
-				}
+			d.md.setField(path, e.Pos, action)
+		}
 
-				if strings.Contains(nextLine, ":") {
-					return errors.New(fmt.Sprintf("could not decode line %d, expected value", d.line))
-				}
+		if d.merge && !f.Append {
+			f.Value.Set(reflect.MakeSlice(f.Value.Type(), 0, len(n.Items)))
+		}
 
-				if len(matches) == 2 {
-					matches[1] = nextLine
-				} else {
-					matches = append(matches, nextLine)
-				}
+		for _, item := range n.Items {
+			if item.Kind != ScalarNode {
+				return errors.New(fmt.Sprintf("could not parse nested array or inner struct into slice element on line %d", item.Pos.Line))
 			}
 
-			// we have the key and value
-			if len(matches) == 2 {
-				key := strings.TrimSpace(matches[0])
-				value := strings.TrimSpace(matches[1])
-
-				// remove comments
-				commentPos := strings.Index(value, "#")
-				if commentPos >= 0 {
-					value = strings.TrimSpace(value[0:commentPos])
-				}
-
-				startArrayPos := strings.Index(value, "[")
-				endArrayPos := strings.Index(value, "]")
-				startInnerPos := strings.Index(value, "{")
-				endInnerPos := strings.Index(value, "}")
-
-				var lines []string
-
-				// multi-line array
-				if startArrayPos >= 0 && endArrayPos < 0 {
-					numberOfBrackets := 1
-
-					for {
-						line, err := d.readNextValidLine()
-						if err != nil {
-							return err
-						}
-
-						line = strings.TrimSpace(line)
-						line = removeCommaFromEnd(line)
-
-						if strings.Contains(line, "[") {
-							numberOfBrackets++
-						}
-
-						endArrayPos := strings.Index(line, "]")
-						if endArrayPos >= 0 {
-							numberOfBrackets--
-							line = line[0:endArrayPos]
-						}
-
-						if len(line) > 0 {
-							lines = append(lines, line)
-						}
-
-						if numberOfBrackets == 0 {
-							break
-						}
-					}
-				}
-
-				// one-line array
-				if startArrayPos >= 0 && endArrayPos >= 0 {
-					value = strings.TrimSpace(value[startArrayPos+1 : endArrayPos])
-					lines = strings.Split(value, " ")
-
-					// maybe lines without space?
-					if len(lines) == 1 {
-						lines = strings.Split(value, ",")
-					}
-
-					for i := range lines {
-						lines[i] = removeCommaFromEnd(lines[i])
-					}
-				}
-
-				var innerData string
-
-				// multi-line inner
-				if startInnerPos >= 0 && endInnerPos < 0 {
-					numberOfCurlyBraces := 1
-
-					for {
-						line, err := d.readNextValidLine()
-						if err != nil {
-							return err
-						}
-
-						if strings.Contains(line, "{") {
-							numberOfCurlyBraces++
-						}
-
-						endInnerPos := strings.Index(line, "}")
-						if endInnerPos >= 0 {
-							numberOfCurlyBraces--
-							line = line[0:endInnerPos]
-						}
-
-						if len(line) > 0 {
-							innerData += line + "\n"
-						}
-
-						if numberOfCurlyBraces == 0 {
-							break
-						}
-					}
-				}
-
-				// one-line inner
-				if startInnerPos >= 0 && endInnerPos >= 0 {
-					innerData = strings.TrimSpace(value[startArrayPos+1 : endArrayPos])
-				}
-
-				isArray := len(lines) > 0
-				isInner := len(innerData) > 0
-
-				for _, f := range fields {
-					if f.Tag == "-" {
-						continue
-					}
-
-					if f.Tag == key {
-
-						if isArray && !f.IsArray {
-							return errors.New(fmt.Sprintf("could not parse array into non-slice type on line %d", d.line))
-						}
-
-						if isArray && f.IsArray {
-							for _, line := range lines {
-								err := setSliceValue(f, unwrapQuotationMarks(line))
-								if err != nil {
-									return errors.Wrap(err, fmt.Sprintf("could not parse line %d", d.line))
-								}
-							}
-							continue
-						}
-
-						if isInner && !f.IsInner {
-							return errors.New(fmt.Sprintf("could not parse inner struct into non-struct type on line %d", d.line))
-						}
-
-						if isInner && f.IsInner {
-							err := Unmarshal([]byte(innerData), f.Value.Addr().Interface())
-							if err != nil {
-								return errors.Wrap(err, fmt.Sprintf("could not parse inner struct"))
-							}
-							continue
-						}
-
-						// simple value
-						err := setValue(f, unwrapQuotationMarks(removeCommaFromEnd(value)))
-						if err != nil {
-							return errors.Wrap(err, fmt.Sprintf("could not parse line %d", d.line))
-						}
-					}
-				}
-			} else {
-				return errors.New(fmt.Sprintf("could not parse line %d", d.line))
+			value, err := d.scalar(item)
+			if err != nil {
+				return err
 			}
-		} else {
-			return errors.New(fmt.Sprintf("could not decode line %d", d.line))
+
+			err = setSliceValue(f, value)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("could not parse line %d", item.Pos.Line))
+			}
+		}
+
+		return nil
+	case ObjectNode:
+		if !f.IsInner {
+			return errors.New(fmt.Sprintf("could not parse inner struct into non-struct type on line %d", n.Pos.Line))
+		}
+
+		err := d.object(n, f.Value, path)
+		if err != nil {
+			return errors.Wrap(err, "could not parse inner struct")
 		}
+
+		return nil
+	}
+
+	if f.IsArray {
+		return errors.New(fmt.Sprintf("could not parse non-array value into slice type on line %d", n.Pos.Line))
+	}
+
+	if f.IsInner {
+		return errors.New(fmt.Sprintf("could not parse value into struct type on line %d", n.Pos.Line))
+	}
+
+	if d.md != nil {
+		d.md.setField(path, e.Pos, MergeOverride)
+	}
+
+	value, err := d.scalar(n)
+	if err != nil {
+		return err
+	}
+
+	err = setValue(f, value)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not parse line %d", n.Pos.Line))
 	}
 
 	return nil
 }
 
+// scalar returns the value of a scalar node, with variables resolved
+func (d *decodeState) scalar(n *Node) (string, error) {
+	if !d.interpolate {
+		return n.Value, nil
+	}
+
+	pos := n.Pos
+
+	// the value starts after the quotation mark
+	if n.Quote != 0 {
+		pos.Column++
+	}
+
+	return interpolate(n.Value, pos, d.lookup)
+}
+
 // Unmarshal parse the data provided an try to populate the struct pointer
+// and validates it, see Validate
 func Unmarshal(data []byte, v interface{}) error {
-	var d decodeState
-	d.init(data)
-	return d.unmarshal(v)
+	d := decodeState{md: validationMetadata(v)}
+
+	err := d.unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+
+	return validate(v, d.md)
 }
 
 // Marshal returns the CFG encoding of v
@@ -305,19 +236,21 @@ func Marshal(v interface{}) ([]byte, error) {
 
 	rv := reflect.ValueOf(v)
 
-	if rv.Type().Kind() != reflect.Struct {
-		return output.Bytes(), fmt.Errorf("encode target should be a struct, got %s", reflect.TypeOf(rv.Elem()))
+	if !rv.IsValid() || rv.Type().Kind() != reflect.Struct {
+		return output.Bytes(), fmt.Errorf("encode target should be a struct, got %s", reflect.TypeOf(v))
 	}
 
-	fields := extractFields(rv)
+	plan := planOf(rv.Type())
 
 	var lines []string
 
-	for _, field := range fields {
-		if field.Tag == "-" {
+	for i := range plan.fields {
+		if plan.fields[i].tag == "-" || !plan.fields[i].exported {
 			continue
 		}
 
+		field := plan.fields[i].field(rv)
+
 		if field.IsArray {
 			var arr []string
 
@@ -390,54 +323,11 @@ func setValue(f field, value string) error {
 		panic("wrong implementation, use setSliceValue")
 	}
 
-	if f.Kind == reflect.String {
-		f.Value.SetString(value)
-		return nil
-	}
-
-	if f.Kind == reflect.Bool {
-		f.Value.SetBool(boolValue(value))
-		return nil
-	}
-
-	if f.Kind == reflect.Int || f.Kind == reflect.Int8 ||
-		f.Kind == reflect.Int16 || f.Kind == reflect.Int32 ||
-		f.Kind == reflect.Int64 {
-		n, err := intValue(value)
-
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, f.Kind))
-		}
-
-		f.Value.SetInt(n)
-		return nil
-	}
-
-	if f.Kind == reflect.Uint || f.Kind == reflect.Uint8 ||
-		f.Kind == reflect.Uint16 || f.Kind == reflect.Uint32 ||
-		f.Kind == reflect.Uint64 {
-		n, err := uintValue(value)
-
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, f.Kind))
-		}
-
-		f.Value.SetUint(n)
-		return nil
-	}
-
-	if f.Kind == reflect.Float32 || f.Kind == reflect.Float64 {
-		n, err := floatValue(value, f.Value.Type().Bits())
-
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, f.Kind))
-		}
-
-		f.Value.SetFloat(n)
+	if f.set == nil {
 		return nil
 	}
 
-	return nil
+	return f.set(f.Value, value)
 }
 
 func setSliceValue(f field, value string) error {
@@ -445,56 +335,21 @@ func setSliceValue(f field, value string) error {
 		panic("wrong implementation, use setValue")
 	}
 
-	kind := f.Value.Type().Elem().Kind()
-
-	if kind == reflect.String {
-		f.Value.Set(reflect.Append(f.Value, reflect.ValueOf(value)))
-		return nil
-	}
-
-	if kind == reflect.Bool {
-		f.Value.Set(reflect.Append(f.Value, reflect.ValueOf(boolValue(value))))
-		return nil
-	}
-
-	if kind == reflect.Int || kind == reflect.Int8 ||
-		kind == reflect.Int16 || kind == reflect.Int32 ||
-		kind == reflect.Int64 {
-		n, err := intValue(value)
-
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, kind))
-		}
-
-		f.Value.Set(reflect.Append(f.Value, reflect.ValueOf(n)))
-		return nil
-	}
-
-	if kind == reflect.Uint || kind == reflect.Uint8 ||
-		kind == reflect.Uint16 || kind == reflect.Uint32 ||
-		kind == reflect.Uint64 {
-		n, err := uintValue(value)
-
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, kind))
-		}
+	elemType := f.Value.Type().Elem()
 
-		f.Value.Set(reflect.Append(f.Value, reflect.ValueOf(n)))
-		return nil
+	if f.set == nil {
+		return errors.New(fmt.Sprintf("invalid type %s", elemType.Kind()))
 	}
 
-	if kind == reflect.Float32 || kind == reflect.Float64 {
-		n, err := floatValue(value, f.Value.Type().Bits())
+	elem := reflect.New(elemType).Elem()
 
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, kind))
-		}
-
-		f.Value.Set(reflect.Append(f.Value, reflect.ValueOf(n)))
-		return nil
+	err := f.set(elem, value)
+	if err != nil {
+		return err
 	}
 
-	return errors.New(fmt.Sprintf("invalid type %s", kind))
+	f.Value.Set(reflect.Append(f.Value, elem))
+	return nil
 }
 
 func intValue(s string) (int64, error) {
@@ -534,21 +389,3 @@ func boolValue(s string) bool {
 
 	return v
 }
-
-func unwrapQuotationMarks(input string) string {
-	last := len(input) - 1
-
-	if (input[0] == '\'' && input[last] == '\'') ||
-		(input[0] == '"' && input[last] == '"') {
-		return input[1:last]
-	}
-
-	return input
-}
-
-func removeCommaFromEnd(input string) string {
-	if input[len(input)-1] == ',' {
-		input = input[0 : len(input)-1]
-	}
-	return input
-}
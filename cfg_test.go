@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -399,6 +400,84 @@ host: "0.0.0.0"`), &v)
 		}
 	})
 
+	t.Run("malformed input does not panic", func(t *testing.T) {
+		inputs := []string{
+			"name: ''",
+			"name: '",
+			"name: ,",
+			"modules: [,]",
+			"modules: [\n,\n]",
+			"modules: ][",
+			"voice: {}",
+			"voice: }{",
+			"internal: secret",
+		}
+
+		for _, input := range inputs {
+			v := struct {
+				Name     string   `cfg:"name"`
+				Modules  []string `cfg:"modules"`
+				internal string
+				Voice    struct {
+					Host string `cfg:"host"`
+				} `cfg:"voice"`
+			}{}
+
+			_ = Unmarshal([]byte(input), &v)
+		}
+	})
+
+	t.Run("typed slices", func(t *testing.T) {
+		v := struct {
+			Ints   []int     `cfg:"ints"`
+			Uints  []uint16  `cfg:"uints"`
+			Floats []float32 `cfg:"floats"`
+		}{}
+
+		err := Unmarshal([]byte(`ints: [1, -2]
+uints: [3, 4]
+floats: [1.5, 2.5]`), &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v.Ints, []int{1, -2}) {
+			t.Fatalf("wrong value decoded, expected [1 -2], got %v", v.Ints)
+		}
+
+		if !reflect.DeepEqual(v.Uints, []uint16{3, 4}) {
+			t.Fatalf("wrong value decoded, expected [3 4], got %v", v.Uints)
+		}
+
+		if !reflect.DeepEqual(v.Floats, []float32{1.5, 2.5}) {
+			t.Fatalf("wrong value decoded, expected [1.5 2.5], got %v", v.Floats)
+		}
+	})
+
+	t.Run("errors report the line in the original input", func(t *testing.T) {
+		v := struct {
+			Voice struct {
+				Inner struct {
+					Value []int `cfg:"value"`
+				} `cfg:"inner"`
+			} `cfg:"voice"`
+		}{}
+
+		err := Unmarshal([]byte(`# comment
+voice: {
+  inner: {
+    value: 10
+  }
+}`), &v)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if !strings.Contains(err.Error(), "line 4") {
+			t.Fatalf("expected error on line 4, got %q", err)
+		}
+	})
+
 	t.Run("complete example", func(t *testing.T) {
 		v := struct {
 			Name         string   `cfg:"name"`
@@ -575,6 +654,18 @@ TestFloat: 3.140000` {
 		}
 	})
 
+	t.Run("encode invalid value", func(t *testing.T) {
+		_, err := Marshal(nil)
+		if err == nil {
+			t.Fail()
+		}
+
+		_, err = Marshal(10)
+		if err == nil {
+			t.Fail()
+		}
+	})
+
 	t.Run("encode inner struct", func(t *testing.T) {
 		v := struct {
 			StructSlice struct {
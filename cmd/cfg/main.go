@@ -0,0 +1,328 @@
+// Command cfg reads and edits CFG files from scripts, keeping comments.
+//
+// Usage:
+//
+//	cfg get [-f file] path
+//	cfg set [-f file] [-string] path value
+//	cfg delete [-f file] path
+//	cfg append [-f file] [-string] path value
+//	cfg convert [-f file] [-to json|cfg]
+//
+// Paths are dot separated keys where array elements are selected by index,
+// like voice.externalPort or resources[0]. The file is server.cfg unless
+// set with -f, - reads the standard input and writes edits to the standard
+// output. Edited files are rewritten in the format of cfgfmt.
+//
+// Values are written by type: numbers and booleans unquoted, arrays and
+// inner structs like [chat, race] parsed as CFG and anything else quoted,
+// -string quotes any value.
+//
+// convert writes the file converted to JSON, or to CFG when it is a .json
+// file, see cfg.ToJSON and cfg.FromJSON. The format is set with -to.
+//
+// The exit code is 1 when the path does not exist and 2 on other errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/crossworth/cfg"
+	"github.com/pkg/errors"
+)
+
+// defaultFile is the file read when -f is not set
+const defaultFile = "server.cfg"
+
+// stdio is the file name of the standard input and output
+const stdio = "-"
+
+// options are the flags of the commands
+type options struct {
+	file string
+	str  bool
+	to   string
+}
+
+// command is a subcommand, args are the names of its arguments
+type command struct {
+	args []string
+	// value reports whether the last argument is a value, which can be
+	// forced to a string with -string
+	value bool
+	// edit changes the document, nil for commands that only read it
+	edit func(root *cfg.Node, args []string, value *cfg.Node) error
+	// read writes the result of the command, nil for edit commands
+	read func(w io.Writer, root *cfg.Node, args []string) error
+	// convert writes data converted to the format to, it reads data
+	// without parsing it as CFG
+	convert func(w io.Writer, data []byte, to string) error
+}
+
+var commands = map[string]*command{
+	"get": {
+		args: []string{"path"},
+		read: get,
+	},
+	"set": {
+		args:  []string{"path", "value"},
+		value: true,
+		edit: func(root *cfg.Node, args []string, value *cfg.Node) error {
+			return root.Set(args[0], value)
+		},
+	},
+	"delete": {
+		args: []string{"path"},
+		edit: func(root *cfg.Node, args []string, value *cfg.Node) error {
+			return root.Delete(args[0])
+		},
+	},
+	"append": {
+		args:  []string{"path", "value"},
+		value: true,
+		edit: func(root *cfg.Node, args []string, value *cfg.Node) error {
+			return root.Append(args[0], value)
+		},
+	},
+	"convert": {
+		convert: convert,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || commands[args[0]] == nil {
+		usage(stderr)
+		return 2
+	}
+
+	name := args[0]
+	c := commands[name]
+
+	fs := flag.NewFlagSet("cfg "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var opts options
+
+	fs.StringVar(&opts.file, "f", defaultFile, "file to read and edit, - for the standard input and output")
+	fs.BoolVar(&opts.str, "string", false, "write the value as a string, for set and append")
+	fs.StringVar(&opts.to, "to", "", "format to convert to, json or cfg, for convert")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s\n", commandUsage(name, c))
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if fs.NArg() != len(c.args) || opts.str && !c.value || len(opts.to) > 0 && c.convert == nil {
+		fs.Usage()
+		return 2
+	}
+
+	err := execute(c, fs.Args(), opts, stdin, stdout)
+
+	if _, ok := errors.Cause(err).(*cfg.PathError); ok {
+		fmt.Fprintln(stderr, "cfg:", err)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "cfg:", err)
+		return 2
+	}
+
+	return 0
+}
+
+func usage(w io.Writer) {
+	var names []string
+
+	for name, c := range commands {
+		names = append(names, "\t"+commandUsage(name, c))
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "usage:\n%s\n", strings.Join(names, "\n"))
+}
+
+func commandUsage(name string, c *command) string {
+	return strings.TrimSpace(fmt.Sprintf("cfg %s [flags] %s", name, strings.Join(c.args, " ")))
+}
+
+func execute(c *command, args []string, opts options, stdin io.Reader, stdout io.Writer) error {
+	var value *cfg.Node
+
+	if c.value {
+		var err error
+
+		value, err = parseValue(args[len(args)-1], opts.str)
+		if err != nil {
+			return err
+		}
+	}
+
+	file := opts.file
+
+	data, err := readFile(file, stdin)
+	if err != nil {
+		return err
+	}
+
+	if c.convert != nil {
+		to := opts.to
+
+		if len(to) == 0 {
+			to = "json"
+
+			if strings.HasSuffix(file, ".json") {
+				to = "cfg"
+			}
+		}
+
+		return c.convert(stdout, data, to)
+	}
+
+	root, err := cfg.ParseDocument(data)
+	if err != nil {
+		return errors.Wrap(err, file)
+	}
+
+	if c.read != nil {
+		return c.read(stdout, root, args)
+	}
+
+	err = c.edit(root, args, value)
+	if err != nil {
+		return err
+	}
+
+	out := cfg.FormatNode(root)
+
+	if file == stdio {
+		_, err = stdout.Write(out)
+		return err
+	}
+
+	return cfg.WriteFileAtomic(file, out, nil)
+}
+
+func readFile(file string, stdin io.Reader) ([]byte, error) {
+	if file == stdio {
+		return ioutil.ReadAll(stdin)
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+// get writes the value at the path args[0], values are written as they
+// are, arrays of values one per line and inner structs as CFG
+func get(w io.Writer, root *cfg.Node, args []string) error {
+	n, err := root.Lookup(args[0])
+	if err != nil {
+		return err
+	}
+
+	var out string
+
+	switch {
+	case n.Kind == cfg.ScalarNode:
+		out = n.Value + "\n"
+	case n.Kind == cfg.ObjectNode:
+		out = string(cfg.FormatNode(n))
+	case scalars(n.Items):
+		for _, item := range n.Items {
+			out += item.Value + "\n"
+		}
+	default:
+		out = formatValue(n)
+	}
+
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+func scalars(items []*cfg.Node) bool {
+	for _, item := range items {
+		if item.Kind != cfg.ScalarNode {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatValue returns n as written by cfg.FormatNode as the value of a key
+func formatValue(n *cfg.Node) string {
+	root := &cfg.Node{
+		Kind:    cfg.ObjectNode,
+		Entries: []*cfg.Entry{{Key: "value", Value: n}},
+	}
+
+	return strings.TrimPrefix(string(cfg.FormatNode(root)), "value: ")
+}
+
+var number = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// parseValue returns the node of the value s given on the command line,
+// see the package documentation
+func parseValue(s string, str bool) (*cfg.Node, error) {
+	if !str && (number.MatchString(s) || s == "true" || s == "false") {
+		return &cfg.Node{Kind: cfg.ScalarNode, Value: s}, nil
+	}
+
+	if !str && (strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{")) {
+		root, err := cfg.ParseDocument([]byte("value: " + s))
+		if err != nil || len(root.Entries) != 1 {
+			return nil, fmt.Errorf("could not parse %s as an array or inner struct, use -string to write it as a string", s)
+		}
+
+		return root.Entries[0].Value, nil
+	}
+
+	if strings.Contains(s, "\n") {
+		return nil, fmt.Errorf("%q can't be written, values can't contain a new line", s)
+	}
+
+	if strings.Contains(s, "'") && strings.Contains(s, `"`) {
+		return nil, fmt.Errorf("%s can't be written, values can't contain both ' and \"", s)
+	}
+
+	return &cfg.Node{Kind: cfg.ScalarNode, Value: s, Quote: '\''}, nil
+}
+
+// convert writes data converted to JSON when to is json, or from JSON to
+// CFG when to is cfg
+func convert(w io.Writer, data []byte, to string) error {
+	var out []byte
+	var err error
+
+	switch to {
+	case "json":
+		out, err = cfg.ToJSON(data)
+		out = append(out, '\n')
+	case "cfg":
+		out, err = cfg.FromJSON(data)
+	default:
+		return fmt.Errorf("unknown format %s, expected json or cfg", to)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const server = `# the server
+name: 'TestServer'
+players: 1024
+voice: {
+  externalPort: 7798
+}
+resources: [
+  chat,
+  race
+]
+# only needed when announce: true
+token: 'abc'
+modules: [
+  {
+    name: js
+  }
+]
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		stdout   string
+		expected string
+	}{
+		{
+			name:   "get value",
+			args:   []string{"get", "voice.externalPort"},
+			stdout: "7798\n",
+		},
+		{
+			name:   "get array",
+			args:   []string{"get", "resources"},
+			stdout: "chat\nrace\n",
+		},
+		{
+			name:   "get inner struct",
+			args:   []string{"get", "voice"},
+			stdout: "externalPort: 7798\n",
+		},
+		{
+			name:   "get array of inner structs",
+			args:   []string{"get", "modules"},
+			stdout: "[\n  {\n    name: js\n  }\n]\n",
+		},
+		{
+			name: "get missing",
+			args: []string{"get", "voice.port"},
+			code: 1,
+		},
+		{
+			name:     "set number",
+			args:     []string{"set", "players", "2048"},
+			expected: strings.Replace(server, "players: 1024", "players: 2048", 1),
+		},
+		{
+			name:     "set string",
+			args:     []string{"set", "name", "My Server"},
+			expected: strings.Replace(server, "name: 'TestServer'", "name: 'My Server'", 1),
+		},
+		{
+			name:     "set forced string",
+			args:     []string{"set", "-string", "announce", "true"},
+			expected: server + "announce: 'true'\n",
+		},
+		{
+			name:     "set new inner struct",
+			args:     []string{"set", "voice.bitrate", "64000"},
+			expected: strings.Replace(server, "  externalPort: 7798\n", "  externalPort: 7798\n  bitrate: 64000\n", 1),
+		},
+		{
+			name:     "set array",
+			args:     []string{"set", "tags", "[a, 'b c']"},
+			expected: server + "tags: [\n  a,\n  'b c'\n]\n",
+		},
+		{
+			name: "set invalid array",
+			args: []string{"set", "tags", "[a, b"},
+			code: 2,
+		},
+		{
+			name: "set both quotes",
+			args: []string{"set", "name", `it's "quoted"`},
+			code: 2,
+		},
+		{
+			name: "set new line",
+			args: []string{"set", "-string", "name", "a\nb"},
+			code: 2,
+		},
+		{
+			name: "set missing element",
+			args: []string{"set", "resources[2]", "admin"},
+			code: 1,
+		},
+		{
+			name:     "delete",
+			args:     []string{"delete", "token"},
+			expected: strings.Replace(server, "token: 'abc'\n", "", 1),
+		},
+		{
+			name: "delete missing",
+			args: []string{"delete", "password"},
+			code: 1,
+		},
+		{
+			name:     "append",
+			args:     []string{"append", "resources", "admin"},
+			expected: strings.Replace(server, "  race\n", "  race,\n  'admin'\n", 1),
+		},
+		{
+			name: "append to a value",
+			args: []string{"append", "name", "admin"},
+			code: 1,
+		},
+		{
+			name: "unknown command",
+			args: []string{"move", "a", "b"},
+			code: 2,
+		},
+		{
+			name: "missing arguments",
+			args: []string{"set", "players"},
+			code: 2,
+		},
+		{
+			name: "string without value",
+			args: []string{"get", "-string", "players"},
+			code: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cfg")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "server.cfg")
+
+			err = ioutil.WriteFile(path, []byte(server), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args := test.args
+
+			if len(args) > 1 {
+				args = append([]string{args[0], "-f", path}, args[1:]...)
+			}
+
+			var stdout, stderr bytes.Buffer
+
+			code := run(args, nil, &stdout, &stderr)
+
+			if code != test.code || stdout.String() != test.stdout {
+				t.Fatalf("expected %d %q, got %d %q %q", test.code, test.stdout, code, stdout.String(), stderr.String())
+			}
+
+			if len(test.expected) == 0 {
+				test.expected = server
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != test.expected {
+				t.Fatalf("expected file\n%s\ngot\n%s", test.expected, data)
+			}
+		})
+	}
+}
+
+func TestRunKeepsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.cfg")
+
+	err = ioutil.WriteFile(path, []byte(server), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"set", "-f", path, "players", "2048"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected 0, got %d %q", code, stderr.String())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("expected only server.cfg, got %d files", len(files))
+	}
+}
+
+func TestRunStdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"set", "-f", "-", "port", "7788"}, strings.NewReader("name: x # the name\n"), &stdout, &stderr)
+
+	expected := "name: x # the name\nport: 7788\n"
+
+	if code != 0 || stdout.String() != expected {
+		t.Fatalf("expected %q, got %d %q %q", expected, code, stdout.String(), stderr.String())
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "panel.json")
+
+	err = ioutil.WriteFile(path, []byte(`{"name": "TestServer", "players": 2048, "resources": ["chat"]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		expected string
+	}{
+		{
+			name:     "to json",
+			args:     []string{"convert", "-f", "-"},
+			stdin:    "# the server\nname: 'TestServer'\nplayers: 2048\n",
+			expected: "{\n  \"name\": \"TestServer\",\n  \"players\": 2048\n}\n",
+		},
+		{
+			name:     "json file",
+			args:     []string{"convert", "-f", path},
+			expected: "name: 'TestServer'\nplayers: 2048\nresources: [\n  'chat'\n]\n",
+		},
+		{
+			name:     "to cfg",
+			args:     []string{"convert", "-f", "-", "-to", "cfg"},
+			stdin:    `{"a": true}`,
+			expected: "a: true\n",
+		},
+		{
+			name:  "unknown format",
+			args:  []string{"convert", "-f", "-", "-to", "yaml"},
+			stdin: "a: b",
+			code:  2,
+		},
+		{
+			name:  "invalid json",
+			args:  []string{"convert", "-f", "-", "-to", "cfg"},
+			stdin: `{"a": null}`,
+			code:  2,
+		},
+		{
+			name: "to on another command",
+			args: []string{"get", "-to", "json", "name"},
+			code: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+			if code != test.code || stdout.String() != test.expected {
+				t.Fatalf("expected %d %q, got %d %q %q", test.code, test.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Command cfgfmt formats CFG files.
+//
+// Usage:
+//
+//	cfgfmt [flags] [path ...]
+//
+// Without paths it formats the standard input. Directories are walked for
+// .cfg files. Without flags the formatted files are written to the
+// standard output. See cfg.Format for the canonical format.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/crossworth/cfg"
+	"github.com/crossworth/cfg/internal/diff"
+)
+
+type options struct {
+	list  bool
+	write bool
+	diff  bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run formats the paths in args and returns the exit code, 2 when any
+// file could not be formatted
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cfgfmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var opts options
+
+	fs.BoolVar(&opts.list, "l", false, "list files whose formatting differs from cfgfmt's")
+	fs.BoolVar(&opts.write, "w", false, "write result to the source file instead of stdout")
+	fs.BoolVar(&opts.diff, "d", false, "display diffs instead of rewriting files")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cfgfmt [flags] [path ...]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		if opts.write {
+			fmt.Fprintln(stderr, "cfgfmt: cannot use -w with standard input")
+			return 2
+		}
+
+		data, err := ioutil.ReadAll(stdin)
+		if err == nil {
+			err = process("<standard input>", data, opts, stdout)
+		}
+
+		if err != nil {
+			fmt.Fprintln(stderr, "cfgfmt:", err)
+			return 2
+		}
+
+		return 0
+	}
+
+	code := 0
+
+	for _, path := range fs.Args() {
+		for _, err := range walk(path, opts, stdout) {
+			fmt.Fprintln(stderr, "cfgfmt:", err)
+			code = 2
+		}
+	}
+
+	return code
+}
+
+// walk formats the file at path, or every .cfg file when it is a directory,
+// and returns the error of every file that could not be formatted
+func walk(path string, opts options, stdout io.Writer) []error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return []error{err}
+	}
+
+	if !info.IsDir() {
+		if err := processFile(path, opts, stdout); err != nil {
+			return []error{err}
+		}
+
+		return nil
+	}
+
+	var errs []error
+
+	err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || filepath.Ext(path) != ".cfg" {
+			return nil
+		}
+
+		if err := processFile(path, opts, stdout); err != nil {
+			errs = append(errs, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func processFile(path string, opts options, stdout io.Writer) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return process(path, data, opts, stdout)
+}
+
+// process formats data read from path and handles the result as asked by opts
+func process(path string, data []byte, opts options, stdout io.Writer) error {
+	out, err := cfg.Format(data)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	changed := !bytes.Equal(data, out)
+
+	if changed && opts.list {
+		fmt.Fprintln(stdout, path)
+	}
+
+	if changed && opts.write {
+		err = cfg.WriteFileAtomic(path, out, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if changed && opts.diff {
+		_, err = stdout.Write(diff.Unified(path+".orig", path, data, out))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.list && !opts.write && !opts.diff {
+		_, err = stdout.Write(out)
+		return err
+	}
+
+	return nil
+}
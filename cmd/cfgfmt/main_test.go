@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unformatted = "name: \"TestServer\",\nport: 7788, # the port\n"
+
+const formatted = "name: 'TestServer'\nport: 7788 # the port\n"
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfgfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"server.cfg":                  unformatted,
+		"resources/chat/resource.cfg": formatted,
+		"resources/race/resource.cfg": unformatted,
+		"resources/race/notes.txt":    unformatted,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := filepath.Join(dir, "server.cfg")
+	race := filepath.Join(dir, "resources", "race", "resource.cfg")
+
+	t.Run("stdout", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{server}, nil, &stdout, &stderr)
+
+		if code != 0 || stdout.String() != formatted {
+			t.Fatalf("expected formatted output, got %d %q %q", code, stdout.String(), stderr.String())
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run(nil, strings.NewReader(unformatted), &stdout, &stderr)
+
+		if code != 0 || stdout.String() != formatted {
+			t.Fatalf("expected formatted output, got %d %q %q", code, stdout.String(), stderr.String())
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{"-l", dir}, nil, &stdout, &stderr)
+
+		expected := race + "\n" + server + "\n"
+
+		if code != 0 || stdout.String() != expected {
+			t.Fatalf("expected %q, got %d %q %q", expected, code, stdout.String(), stderr.String())
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{"-d", server}, nil, &stdout, &stderr)
+
+		expected := "--- " + server + ".orig\n+++ " + server + "\n@@ -1,2 +1,2 @@\n" +
+			"-name: \"TestServer\",\n-port: 7788, # the port\n+name: 'TestServer'\n+port: 7788 # the port\n"
+
+		if code != 0 || stdout.String() != expected {
+			t.Fatalf("expected %q, got %d %q %q", expected, code, stdout.String(), stderr.String())
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{"-w", dir}, nil, &stdout, &stderr)
+		if code != 0 || stdout.Len() > 0 {
+			t.Fatalf("expected no output, got %d %q %q", code, stdout.String(), stderr.String())
+		}
+
+		for _, path := range []string{server, race} {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != formatted {
+				t.Fatalf("expected %s to be formatted, got %q", path, data)
+			}
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "resources", "race", "notes.txt"))
+		if err != nil || string(data) != unformatted {
+			t.Fatalf("expected notes.txt to be kept, got %q", data)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{filepath.Join(dir, "missing.cfg")}, nil, &stdout, &stderr)
+		if code != 2 || stderr.Len() == 0 {
+			t.Fatalf("expected error for missing file, got %d", code)
+		}
+
+		stderr.Reset()
+
+		code = run(nil, strings.NewReader("name: [a"), &stdout, &stderr)
+		if code != 2 || !strings.Contains(stderr.String(), "<standard input>: could not decode line 1") {
+			t.Fatalf("expected syntax error, got %d %q", code, stderr.String())
+		}
+
+		broken := filepath.Join(dir, "broken")
+
+		for _, name := range []string{"a.cfg", "b.cfg"} {
+			err := os.MkdirAll(broken, 0755)
+			if err == nil {
+				err = ioutil.WriteFile(filepath.Join(broken, name), []byte("name: [a"), 0644)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stderr.Reset()
+
+		code = run([]string{broken}, nil, &stdout, &stderr)
+
+		for _, name := range []string{"a.cfg", "b.cfg"} {
+			if code != 2 || !strings.Contains(stderr.String(), filepath.Join(broken, name)+": could not decode line 1") {
+				t.Fatalf("expected syntax error for %s, got %d %q", name, code, stderr.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/crossworth/cfg"
+)
+
+// includeKey is skipped, it is handled by the loader and has no field
+const includeKey = "include"
+
+// initialisms are written in upper case on field names, like golint
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "QPS": true, "RAM": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// kind is an inferred type, the order is used to widen types when
+// samples disagree, int widens to float64 and anything else to string
+type kind int
+
+const (
+	unknownKind kind = iota
+	boolKind
+	intKind
+	floatKind
+	stringKind
+	sliceKind
+	structKind
+)
+
+// typ is the inferred type of a value
+type typ struct {
+	kind kind
+	// elem is the element type of slices
+	elem *typ
+	// fields of structs, in key order
+	fields []*field
+}
+
+type field struct {
+	key string
+	typ *typ
+}
+
+func (t *typ) field(key string) *field {
+	for _, f := range t.fields {
+		if f.key == key {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// infer returns the type of the node n
+func infer(n *cfg.Node, path string) (*typ, error) {
+	switch n.Kind {
+	case cfg.ObjectNode:
+		t := &typ{kind: structKind}
+
+		for _, e := range n.Entries {
+			if len(path) == 0 && e.Key == includeKey {
+				continue
+			}
+
+			err := t.add(e, joinPath(path, e.Key))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return t, nil
+	case cfg.ArrayNode:
+		elem := &typ{kind: unknownKind}
+
+		for _, item := range n.Items {
+			if item.Kind != cfg.ScalarNode {
+				return nil, fmt.Errorf("%s on line %d, arrays can only hold values", path, item.Pos.Line)
+			}
+
+			elem = widen(elem, scalarType(item))
+		}
+
+		// empty arrays stay unknown so other samples can set the type,
+		// they are generated as strings
+		return &typ{kind: sliceKind, elem: elem}, nil
+	}
+
+	return scalarType(n), nil
+}
+
+// add merges the key value pair e into the struct type t
+func (t *typ) add(e *cfg.Entry, path string) error {
+	next, err := infer(e.Value, path)
+	if err != nil {
+		return err
+	}
+
+	f := t.field(e.Key)
+	if f == nil {
+		t.fields = append(t.fields, &field{key: e.Key, typ: next})
+		return nil
+	}
+
+	f.typ, err = merge(f.typ, next, path)
+	return err
+}
+
+// merge returns the type that holds both a and b
+func merge(a *typ, b *typ, path string) (*typ, error) {
+	switch {
+	case a.kind == structKind && b.kind == structKind:
+		for _, f := range b.fields {
+			current := a.field(f.key)
+
+			if current == nil {
+				a.fields = append(a.fields, f)
+				continue
+			}
+
+			t, err := merge(current.typ, f.typ, joinPath(path, f.key))
+			if err != nil {
+				return nil, err
+			}
+
+			current.typ = t
+		}
+
+		return a, nil
+	case a.kind == sliceKind && b.kind == sliceKind:
+		return &typ{kind: sliceKind, elem: widen(a.elem, b.elem)}, nil
+	case a.kind >= sliceKind || b.kind >= sliceKind:
+		return nil, fmt.Errorf("%s is %s on one sample and %s on another", path, a.kind, b.kind)
+	}
+
+	return widen(a, b), nil
+}
+
+// widen returns the scalar type that holds both a and b
+func widen(a *typ, b *typ) *typ {
+	switch {
+	case a.kind == b.kind || b.kind == unknownKind:
+		return a
+	case a.kind == unknownKind:
+		return b
+	case (a.kind == intKind || a.kind == floatKind) && (b.kind == intKind || b.kind == floatKind):
+		return &typ{kind: floatKind}
+	}
+
+	return &typ{kind: stringKind}
+}
+
+func (k kind) String() string {
+	switch k {
+	case sliceKind:
+		return "an array"
+	case structKind:
+		return "an inner struct"
+	}
+
+	return "a value"
+}
+
+// scalarType infers the type of a scalar node, quoted values are strings
+func scalarType(n *cfg.Node) *typ {
+	if n.Quote != 0 {
+		return &typ{kind: stringKind}
+	}
+
+	switch n.Value {
+	case "true", "false":
+		return &typ{kind: boolKind}
+	}
+
+	if _, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+		return &typ{kind: intKind}
+	}
+
+	// ParseFloat also accepts values like NaN and Inf
+	if _, err := strconv.ParseFloat(n.Value, 64); err == nil && strings.ContainsAny(n.Value, "0123456789") {
+		return &typ{kind: floatKind}
+	}
+
+	return &typ{kind: stringKind}
+}
+
+func joinPath(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// fieldName returns an exported Go identifier for key, like ExternalPort
+// for externalPort or CdnURL for cdnUrl
+func fieldName(key string) string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && !unicode.IsUpper(word[len(word)-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+
+	flush()
+
+	var name strings.Builder
+
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(w)
+		name.WriteRune(unicode.ToUpper(runes[0]))
+		name.WriteString(string(runes[1:]))
+	}
+
+	if name.Len() == 0 || unicode.IsDigit([]rune(name.String())[0]) {
+		return "X" + name.String()
+	}
+
+	return name.String()
+}
+
+// options configures the generated code
+type options struct {
+	pkg      string
+	typeName string
+	sources  []string
+	command  string
+}
+
+// generate returns the gofmt formatted Go source of the struct type t
+func generate(t *typ, opts options) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by %s; DO NOT EDIT.\n\n", opts.command)
+	fmt.Fprintf(&buf, "package %s\n\n", opts.pkg)
+	fmt.Fprintf(&buf, "// %s is the config of %s\n", opts.typeName, strings.Join(opts.sources, ", "))
+	fmt.Fprintf(&buf, "type %s ", opts.typeName)
+
+	writeType(&buf, t)
+	buf.WriteString("\n")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format generated code, %s", err)
+	}
+
+	return out, nil
+}
+
+func writeType(buf *bytes.Buffer, t *typ) {
+	switch t.kind {
+	case boolKind:
+		buf.WriteString("bool")
+	case intKind:
+		buf.WriteString("int")
+	case floatKind:
+		buf.WriteString("float64")
+	case sliceKind:
+		buf.WriteString("[]")
+		writeType(buf, t.elem)
+	case structKind:
+		buf.WriteString("struct {\n")
+
+		// keys like use_cdn and useCdn have the same name, the first one
+		// keeps it and the others get a number not used by another key
+		reserved := make(map[string]bool, len(t.fields))
+		used := make(map[string]bool, len(t.fields))
+
+		for _, f := range t.fields {
+			reserved[fieldName(f.key)] = true
+		}
+
+		for _, f := range t.fields {
+			base := fieldName(f.key)
+			name := base
+
+			for i := 2; used[name]; i++ {
+				name = fmt.Sprintf("%s%d", base, i)
+
+				if reserved[name] {
+					name = base
+				}
+			}
+
+			used[name] = true
+
+			buf.WriteString(name)
+			buf.WriteString(" ")
+			writeType(buf, f.typ)
+			fmt.Fprintf(buf, " `cfg:%s`\n", strconv.Quote(f.key))
+		}
+
+		buf.WriteString("}")
+	default:
+		buf.WriteString("string")
+	}
+}
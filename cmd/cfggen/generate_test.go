@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crossworth/cfg"
+)
+
+func TestGenerate(t *testing.T) {
+	samples := []string{
+		`name: "TestServer"
+port: 7788
+include: local.cfg
+ratio: 1
+debug: false
+tags: [a, b]
+empty: []
+ids: []
+voice: {
+  bitrate: 64000
+  external-host: localhost
+}`,
+		`ratio: 1.5
+port: "7788"
+version: 1.0.2
+levels: [1, 2.5]
+voice: {
+  externalPort: 7798
+}
+2fa: true
+ids: [1, 2]
+use_cdn: true
+useCdn: false
+useCdn2: true`,
+	}
+
+	var types []*typ
+
+	for _, sample := range samples {
+		root, err := cfg.Parse([]byte(sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		typ, err := infer(root, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		types = append(types, typ)
+	}
+
+	merged, err := merge(types[0], types[1], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := generate(merged, options{pkg: "config", typeName: "Server", sources: []string{"a.cfg", "b.cfg"}, command: "cfggen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "// Code generated by cfggen; DO NOT EDIT.\n\npackage config\n\n// Server is the config of a.cfg, b.cfg\ntype Server struct {\n" +
+		"\tName  string   `cfg:\"name\"`\n" +
+		"\tPort  string   `cfg:\"port\"`\n" +
+		"\tRatio float64  `cfg:\"ratio\"`\n" +
+		"\tDebug bool     `cfg:\"debug\"`\n" +
+		"\tTags  []string `cfg:\"tags\"`\n" +
+		"\tEmpty []string `cfg:\"empty\"`\n" +
+		"\tIds   []int    `cfg:\"ids\"`\n" +
+		"\tVoice struct {\n" +
+		"\t\tBitrate      int    `cfg:\"bitrate\"`\n" +
+		"\t\tExternalHost string `cfg:\"external-host\"`\n" +
+		"\t\tExternalPort int    `cfg:\"externalPort\"`\n" +
+		"\t} `cfg:\"voice\"`\n" +
+		"\tVersion string    `cfg:\"version\"`\n" +
+		"\tLevels  []float64 `cfg:\"levels\"`\n" +
+		"\tX2fa    bool      `cfg:\"2fa\"`\n" +
+		"\tUseCdn  bool      `cfg:\"use_cdn\"`\n" +
+		"\tUseCdn3 bool      `cfg:\"useCdn\"`\n" +
+		"\tUseCdn2 bool      `cfg:\"useCdn2\"`\n" +
+		"}\n"
+
+	if string(out) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	root, err := cfg.Parse([]byte("voice: {\n  hosts: [{ a: 1 }]\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = infer(root, "")
+	if err == nil || err.Error() != "voice.hosts on line 2, arrays can only hold values" {
+		t.Fatalf("expected error for array of inner structs, got %v", err)
+	}
+
+	a, _ := cfg.Parse([]byte("voice: 1"))
+	b, _ := cfg.Parse([]byte("voice: { port: 1 }"))
+
+	ta, _ := infer(a, "")
+	tb, _ := infer(b, "")
+
+	_, err = merge(ta, tb, "")
+	if err == nil || err.Error() != "voice is a value on one sample and an inner struct on another" {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"name":               "Name",
+		"externalPublicHost": "ExternalPublicHost",
+		"cdnUrl":             "CdnURL",
+		"use_early_auth":     "UseEarlyAuth",
+		"HTTPPort":           "HTTPPort",
+		"id":                 "ID",
+		"1st":                "X1st",
+		"-":                  "X",
+	} {
+		if name := fieldName(key); name != expected {
+			t.Errorf("expected %s for %q, got %s", expected, key, name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfggen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "config.go")
+	example := filepath.Join("..", "..", "example", "example.cfg")
+
+	err = run([]string{"-package", "config", "-o", output, example})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "package config") || !strings.Contains(string(data), "ExternalPublicPort int    `cfg:\"externalPublicPort\"`") {
+		t.Fatalf("wrong output, got %s", data)
+	}
+
+	err = run([]string{example, example})
+	if err == nil || !strings.Contains(err.Error(), "-merge") {
+		t.Fatalf("expected error without -merge, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Command cfggen generates a Go struct from sample CFG files.
+//
+// Usage:
+//
+//	cfggen [flags] file.cfg [file.cfg...]
+//
+// The types are inferred from the values: quoted values are strings,
+// unquoted numbers are int or float64 and true and false are bool.
+// Inner structs become nested structs and arrays become slices, keys keep
+// the order of the samples. When more than one sample is given with -merge,
+// the fields of every sample are merged into one type, widening int to
+// float64 and conflicting values to string.
+//
+// It can be used with go generate, the package defaults to $GOPACKAGE:
+//
+//	//go:generate cfggen -type ServerConfig -o server_config.go server.cfg
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/crossworth/cfg"
+)
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cfggen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("cfggen", flag.ContinueOnError)
+
+	typeName := fs.String("type", "Config", "name of the generated type")
+	pkg := fs.String("package", os.Getenv("GOPACKAGE"), "package of the generated file, main when empty")
+	output := fs.String("o", "", "file to write, stdout when empty")
+	mergeSamples := fs.Bool("merge", false, "merge several sample files into one type")
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cfggen [flags] file.cfg [file.cfg...]")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no input files")
+	}
+
+	if fs.NArg() > 1 && !*mergeSamples {
+		return fmt.Errorf("more than one input file, use -merge to merge them into one type")
+	}
+
+	if len(*pkg) == 0 {
+		*pkg = "main"
+	}
+
+	t, err := inferFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	out, err := generate(t, options{
+		pkg:      *pkg,
+		typeName: *typeName,
+		sources:  fs.Args(),
+		command:  "cfggen " + strings.Join(args, " "),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(*output) == 0 {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	return ioutil.WriteFile(*output, out, 0644)
+}
+
+// inferFiles returns the type holding every file
+func inferFiles(paths []string) (*typ, error) {
+	var t *typ
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		root, err := cfg.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		next, err := infer(root, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		if t == nil {
+			t = next
+			continue
+		}
+
+		t, err = merge(t, next, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,249 @@
+// Command cfglint checks CFG files for likely mistakes and inconsistent style.
+//
+// Usage:
+//
+//	cfglint [flags] [path ...]
+//
+// Directories are walked for .cfg files, without paths the standard input
+// is checked. The exit code is 1 when there are findings and 2 when a file
+// could not be read. Findings can be ignored with a # cfglint:ignore
+// comment, see lint.Linter.Lint.
+//
+// With -fix the findings with a mechanical fix are fixed in place, the
+// files with fixes are rewritten in the format of cfgfmt. The fixes are
+// written before the report, to the standard error unless the format is
+// text, and only the findings left are reported. Values that look like
+// booleans are only fixed for the keys -schema describes as booleans.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crossworth/cfg"
+	"github.com/crossworth/cfg/lint"
+)
+
+var writers = map[string]func(w io.Writer, r *lint.Report) error{
+	"text":  lint.WriteText,
+	"json":  lint.WriteJSON,
+	"sarif": lint.WriteSARIF,
+	"junit": lint.WriteJUnit,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cfglint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	format := fs.String("format", "text", "output format: text, json, sarif or junit")
+	schema := fs.String("schema", "", "JSON Schema file to report unknown keys and fix booleans, see cfg.JSONSchema")
+	disable := fs.String("disable", "", "comma separated rules to disable")
+	enable := fs.String("enable", "", "comma separated rules to enable, like the optional sorted-resources")
+	list := fs.Bool("rules", false, "list the rules and exit")
+	fix := fs.Bool("fix", false, "fix the findings that have a mechanical fix in place")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cfglint [flags] [path ...]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	linter, err := newLinter(*schema, *enable, *disable)
+	if err != nil {
+		fmt.Fprintln(stderr, "cfglint:", err)
+		return 2
+	}
+
+	if *list {
+		for _, r := range linter.Rules() {
+			name := r.Name()
+
+			if !linter.Enabled(name) {
+				name += " (off)"
+			}
+
+			fmt.Fprintf(stdout, "%-24s %s\n", name, r.Description())
+		}
+
+		return 0
+	}
+
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "cfglint: unknown format %s\n", *format)
+		return 2
+	}
+
+	report := &lint.Report{Rules: linter.Rules()}
+	code := 0
+
+	fixes := stdout
+	if *format != "text" {
+		fixes = stderr
+	}
+
+	if fs.NArg() == 0 {
+		if *fix {
+			fmt.Fprintln(stderr, "cfglint: cannot use -fix with standard input")
+			return 2
+		}
+
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "cfglint:", err)
+			return 2
+		}
+
+		report.Files = []string{"<standard input>"}
+		report.Findings = linter.Lint(report.Files[0], data)
+	}
+
+	for _, path := range fs.Args() {
+		err := walk(path, func(path string, data []byte) error {
+			if *fix {
+				fixed, err := fixFile(linter, path, data, fixes)
+				if err != nil {
+					return err
+				}
+
+				data = fixed
+			}
+
+			report.Files = append(report.Files, path)
+			report.Findings = append(report.Findings, linter.Lint(path, data)...)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, "cfglint:", err)
+			code = 2
+		}
+	}
+
+	err = write(stdout, report)
+	if err != nil {
+		fmt.Fprintln(stderr, "cfglint:", err)
+		return 2
+	}
+
+	if code == 0 && len(report.Findings) > 0 {
+		code = 1
+	}
+
+	return code
+}
+
+// fixFile fixes the file at path with content data in place, writing
+// the fixes to w, and returns its new content
+func fixFile(linter *lint.Linter, path string, data []byte, w io.Writer) ([]byte, error) {
+	fixed, findings, err := linter.Fix(path, data)
+	if err != nil {
+		// reported by Lint as a syntax finding
+		return data, nil
+	}
+
+	if len(findings) == 0 {
+		return data, nil
+	}
+
+	err = cfg.WriteFileAtomic(path, fixed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fixed, lint.WriteFixes(w, findings)
+}
+
+// newLinter returns a linter with the built-in rules, the optional ones
+// disabled, and the unknown-keys rule when schema is set. The schema also
+// tells the suspicious-bool rule which values can be fixed
+func newLinter(schema string, enable string, disable string) (*lint.Linter, error) {
+	rules := lint.Rules()
+	optional := lint.OptionalRules()
+
+	rules = append(rules, optional...)
+
+	if len(schema) > 0 {
+		data, err := ioutil.ReadFile(schema)
+		if err != nil {
+			return nil, err
+		}
+
+		unknown, err := lint.UnknownKeys(data)
+		if err != nil {
+			return nil, err
+		}
+
+		bools, err := lint.SuspiciousBools(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, r := range rules {
+			if r.Name() == bools.Name() {
+				rules[i] = bools
+			}
+		}
+
+		rules = append(rules, unknown)
+	}
+
+	linter := lint.New(rules...)
+
+	for _, r := range optional {
+		_ = linter.Disable(r.Name())
+	}
+
+	if err := linter.Enable(splitList(enable)...); err != nil {
+		return nil, err
+	}
+
+	if err := linter.Disable(splitList(disable)...); err != nil {
+		return nil, err
+	}
+
+	return linter, nil
+}
+
+func splitList(list string) []string {
+	var names []string
+
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// walk calls fn with the file at path, or every .cfg file when it is a directory
+func walk(path string, fn func(path string, data []byte) error) error {
+	return filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || name != path && filepath.Ext(name) != ".cfg" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		return fn(name, data)
+	})
+}
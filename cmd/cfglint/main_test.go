@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfglint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"server.cfg":                  "name: 'TestServer'\nport: 7788\nprot: 7789\nresources: [race, chat]\n",
+		"resources/chat/resource.cfg": "type: js\nmain: 'index.js'\n",
+		"resources/race/resource.cfg": "type: js\nclientOnly: yes\n",
+		"resources/race/notes.txt":    "clientOnly: yes\n",
+		"schema.json":                 `{"type": "object", "properties": {"name": {}, "port": {}, "resources": {}}}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := filepath.Join(dir, "server.cfg")
+	resources := filepath.Join(dir, "resources")
+	race := filepath.Join(resources, "race", "resource.cfg")
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		code     int
+		expected string
+	}{
+		{
+			name: "clean file",
+			args: []string{server},
+		},
+		{
+			name:     "directory",
+			args:     []string{resources},
+			code:     1,
+			expected: race + ":2:13: warning: yes looks like a boolean, write true (suspicious-bool)\n",
+		},
+		{
+			name: "disabled rule",
+			args: []string{"-disable", "suspicious-bool", resources},
+		},
+		{
+			name:     "optional rule",
+			args:     []string{"-enable", "sorted-resources", server},
+			code:     1,
+			expected: server + ":4:1: info: resources are not sorted, chat should come before race (sorted-resources)\n",
+		},
+		{
+			name:     "schema",
+			args:     []string{"-schema", filepath.Join(dir, "schema.json"), server},
+			code:     1,
+			expected: server + ":3:1: error: unknown key prot, did you mean port? (unknown-keys)\n",
+		},
+		{
+			name:     "stdin",
+			stdin:    "a: []\n",
+			code:     1,
+			expected: "<standard input>:1:1: info: a is an empty array (empty-array)\n",
+		},
+		{
+			name:     "json",
+			args:     []string{"-format", "json", server},
+			expected: "[]\n",
+		},
+		{
+			name: "fix stdin",
+			args: []string{"-fix"},
+			code: 2,
+		},
+		{
+			name: "unknown rule",
+			args: []string{"-disable", "typo", server},
+			code: 2,
+		},
+		{
+			name: "unknown format",
+			args: []string{"-format", "xml", server},
+			code: 2,
+		},
+		{
+			name: "missing file",
+			args: []string{filepath.Join(dir, "missing.cfg")},
+			code: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+			if code != test.code || stdout.String() != test.expected {
+				t.Fatalf("expected %d %q, got %d %q %q", test.code, test.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunFix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfglint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.cfg")
+	data := `# the server
+name: "TestServer"
+password: 'secret'
+debug: yes
+# old port
+port: 7788
+port: 7789
+resources: [race, chat] # cfglint:ignore sorted-resources
+modules: [] # intended
+`
+
+	err = ioutil.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// booleans are only fixed when the schema describes them
+	schema := filepath.Join(dir, "schema.json")
+
+	err = ioutil.WriteFile(schema, []byte(`{"type": "object", "properties": {"debug": {"type": "boolean"}}, "additionalProperties": true}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-fix", "-schema", schema, "-enable", "sorted-resources", path}, nil, &stdout, &stderr)
+
+	expected := path + `:2:7: fixed: value uses double quotes, most values use single quotes (mixed-quotes)
+	- "TestServer"
+	+ 'TestServer'
+` + path + `:4:8: fixed: yes looks like a boolean, write true (suspicious-bool)
+	- yes
+	+ true
+` + path + `:7:1: fixed: port is repeated, first set on line 6 (duplicate-keys)
+	- port: 7788
+` + path + `:11:1: info: modules is an empty array (empty-array)
+`
+
+	if code != 1 || stdout.String() != expected {
+		t.Fatalf("expected %q, got %d %q %q", expected, code, stdout.String(), stderr.String())
+	}
+
+	fixed, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `# the server
+name: 'TestServer'
+password: 'secret'
+debug: true
+# old port
+port: 7789
+resources: [
+  race,
+  chat
+] # cfglint:ignore sorted-resources
+modules: [] # intended
+`
+
+	if string(fixed) != expected {
+		t.Fatalf("expected fixed file\n%s\ngot\n%s", expected, fixed)
+	}
+}
+
+func TestRunRules(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-rules"}, nil, &stdout, &stderr)
+
+	if code != 0 || !strings.Contains(stdout.String(), "duplicate-keys ") || strings.Contains(stdout.String(), "unknown-keys") {
+		t.Fatalf("expected the built-in rules, got %d %q %q", code, stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,135 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Limits restricts the resources a Decoder may use while decoding,
+// useful when the input comes from an untrusted source.
+// A zero value on any field disables that limit.
+type Limits struct {
+	// MaxBytes is the maximum size of the input
+	MaxBytes int64
+	// MaxDepth is the maximum nesting of inner structs ({ ... } blocks)
+	MaxDepth int
+	// MaxLineLength is the maximum length of a single line, in bytes
+	MaxLineLength int
+	// MaxArrayLength is the maximum number of elements of a single array
+	MaxArrayLength int
+	// MaxKeys is the maximum number of keys in the whole input, inner structs included
+	MaxKeys int
+}
+
+// LimitError is returned when the input exceeds one of the Limits
+type LimitError struct {
+	// Limit is the name of the Limits field that was exceeded
+	Limit string
+	// Max is the configured value of the limit
+	Max int64
+	// Line is the line where the limit was exceeded, zero when it
+	// does not apply to a line (MaxBytes)
+	Line int
+}
+
+func (e *LimitError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("input exceeds %s of %d", e.Limit, e.Max)
+	}
+
+	return fmt.Sprintf("input exceeds %s of %d on line %d", e.Limit, e.Max, e.Line)
+}
+
+// Decoder reads and decodes CFG data from an input stream
+type Decoder struct {
+	r           io.Reader
+	limits      Limits
+	md          *Metadata
+	interpolate bool
+	lookup      LookupFunc
+	env         bool
+	envPrefix   string
+	envLookup   LookupFunc
+}
+
+// NewDecoder returns a new decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetLimits sets the resource limits used by Decode
+func (dec *Decoder) SetLimits(limits Limits) {
+	dec.limits = limits
+}
+
+// Interpolate enables variables in values, ${VAR} is replaced by the value
+// of VAR and ${VAR:-default} by default when VAR is not set or empty,
+// $${ is kept as a literal ${. Variables are resolved through lookup,
+// os.LookupEnv is used when lookup is nil
+func (dec *Decoder) Interpolate(lookup LookupFunc) {
+	dec.interpolate = true
+	dec.lookup = lookup
+}
+
+// EnvOverrides enables environment variable overrides, applied to the
+// struct after it is decoded, see ApplyEnv for prefix and lookup
+func (dec *Decoder) EnvOverrides(prefix string, lookup LookupFunc) {
+	dec.env = true
+	dec.envPrefix = prefix
+	dec.envLookup = lookup
+}
+
+// Decode reads the whole input and populates the struct pointed by v
+func (dec *Decoder) Decode(v interface{}) error {
+	r := dec.r
+
+	if dec.limits.MaxBytes > 0 {
+		r = io.LimitReader(r, dec.limits.MaxBytes+1)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "could not read input")
+	}
+
+	if dec.limits.MaxBytes > 0 && int64(len(data)) > dec.limits.MaxBytes {
+		return &LimitError{Limit: "MaxBytes", Max: dec.limits.MaxBytes}
+	}
+
+	d := decodeState{
+		limits:      dec.limits,
+		md:          newMetadata(),
+		interpolate: dec.interpolate,
+		lookup:      dec.lookup,
+	}
+
+	err = d.unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+
+	if dec.env {
+		err = applyEnvOverrides(v, dec.envPrefix, dec.envLookup, d.md)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.md.finish(v)
+
+	err = validate(v, d.md)
+	if err != nil {
+		return err
+	}
+
+	dec.md = d.md
+	return nil
+}
+
+// Metadata returns the metadata of the last successful call to Decode
+func (dec *Decoder) Metadata() *Metadata {
+	return dec.md
+}
@@ -0,0 +1,95 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDecoder(t *testing.T) {
+	type target struct {
+		Name    string   `cfg:"name"`
+		Modules []string `cfg:"modules"`
+		Voice   struct {
+			Host  string `cfg:"host"`
+			Inner struct {
+				Value string `cfg:"value"`
+			} `cfg:"inner"`
+		} `cfg:"voice"`
+	}
+
+	testLimit := func(t *testing.T, input string, limits Limits, limit string, line int) {
+		t.Helper()
+
+		var v target
+
+		dec := NewDecoder(strings.NewReader(input))
+		dec.SetLimits(limits)
+
+		err := dec.Decode(&v)
+
+		limitErr, ok := errors.Cause(err).(*LimitError)
+		if !ok {
+			t.Fatalf("expected *LimitError, got %v", err)
+		}
+
+		if limitErr.Limit != limit {
+			t.Fatalf("wrong limit, expected %s, got %s", limit, limitErr.Limit)
+		}
+
+		if limitErr.Line != line {
+			t.Fatalf("wrong line, expected %d, got %d", line, limitErr.Line)
+		}
+	}
+
+	t.Run("no limits", func(t *testing.T) {
+		var v target
+
+		err := NewDecoder(strings.NewReader("name: " + strings.Repeat("a", 100*1024))).Decode(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(v.Name) != 100*1024 {
+			t.Fatalf("wrong value decoded, expected name with %d bytes, got %d", 100*1024, len(v.Name))
+		}
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		testLimit(t, "name: test", Limits{MaxBytes: 5}, "MaxBytes", 0)
+	})
+
+	t.Run("max line length", func(t *testing.T) {
+		testLimit(t, "name: test\nmodules: [a, b, c]", Limits{MaxLineLength: 12}, "MaxLineLength", 2)
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		testLimit(t, "voice: {\n inner: {\n value: x\n }\n}", Limits{MaxDepth: 1}, "MaxDepth", 2)
+	})
+
+	t.Run("max array length", func(t *testing.T) {
+		testLimit(t, "modules: [a, b, c]", Limits{MaxArrayLength: 2}, "MaxArrayLength", 1)
+		testLimit(t, "modules: [\na\nb\nc\n]", Limits{MaxArrayLength: 2}, "MaxArrayLength", 4)
+	})
+
+	t.Run("max keys", func(t *testing.T) {
+		testLimit(t, "name: test\nvoice: {\n host: x\n}", Limits{MaxKeys: 2}, "MaxKeys", 3)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		var v target
+
+		dec := NewDecoder(strings.NewReader("name: test\nmodules: [a, b]\nvoice: {\n host: x\n}"))
+		dec.SetLimits(Limits{MaxBytes: 1024, MaxDepth: 1, MaxLineLength: 32, MaxArrayLength: 2, MaxKeys: 4})
+
+		err := dec.Decode(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Voice.Host != "x" {
+			t.Fatalf("wrong value decoded, expected x, got %q", v.Voice.Host)
+		}
+	})
+}
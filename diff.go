@@ -0,0 +1,174 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ChangeKind is the kind of a Change
+type ChangeKind string
+
+const (
+	// Added is a key that only exists on the new value
+	Added ChangeKind = "added"
+	// Removed is a key that only exists on the old value
+	Removed ChangeKind = "removed"
+	// Modified is a key whose value changed
+	Modified ChangeKind = "modified"
+)
+
+// Change is a difference between two configs
+type Change struct {
+	// Path is the key path, like voice.externalPort
+	Path string
+	Kind ChangeKind
+	// Old is the previous value, nil when added
+	Old interface{}
+	// New is the current value, nil when removed
+	New interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %v", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("%s removed: %v", c.Path, c.Old)
+	}
+
+	return fmt.Sprintf("%s modified: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff returns the keys added, removed and modified from a to b, in the
+// order they appear. Formatting, comments, quotation marks and the order
+// of the keys are ignored. Repeated keys are merged like Unmarshal does,
+// inner structs key by key and arrays appended, otherwise the last one is
+// used. Arrays are compared as a whole. Values are the same as Node.Interface
+func Diff(a []byte, b []byte) ([]Change, error) {
+	old, err := Parse(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse old config")
+	}
+
+	new, err := Parse(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse new config")
+	}
+
+	var changes []Change
+
+	diffNodes(old, new, "", &changes)
+	return changes, nil
+}
+
+func diffNodes(old *Node, new *Node, path string, changes *[]Change) {
+	if old.Kind != ObjectNode || new.Kind != ObjectNode {
+		if !equalNodes(old, new) {
+			*changes = append(*changes, Change{Path: path, Kind: Modified, Old: old.Interface(), New: new.Interface()})
+		}
+
+		return
+	}
+
+	for _, key := range entryKeys(old) {
+		p := joinPath(path, key)
+		o, n := old.field(key), new.field(key)
+
+		if n == nil {
+			*changes = append(*changes, Change{Path: p, Kind: Removed, Old: o.Interface()})
+			continue
+		}
+
+		diffNodes(o, n, p, changes)
+	}
+
+	for _, key := range entryKeys(new) {
+		if old.field(key) == nil {
+			*changes = append(*changes, Change{Path: joinPath(path, key), Kind: Added, New: new.field(key).Interface()})
+		}
+	}
+}
+
+// entryKeys returns the keys of an object node once, in order
+func entryKeys(n *Node) []string {
+	seen := make(map[string]bool, len(n.Entries))
+	keys := make([]string, 0, len(n.Entries))
+
+	for _, e := range n.Entries {
+		if seen[e.Key] {
+			continue
+		}
+
+		seen[e.Key] = true
+		keys = append(keys, e.Key)
+	}
+
+	return keys
+}
+
+func equalNodes(a *Node, b *Node) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case ArrayNode:
+		if len(a.Items) != len(b.Items) {
+			return false
+		}
+
+		for i := range a.Items {
+			if !equalNodes(a.Items[i], b.Items[i]) {
+				return false
+			}
+		}
+
+		return true
+	case ObjectNode:
+		var changes []Change
+
+		diffNodes(a, b, "", &changes)
+		return len(changes) == 0
+	}
+
+	return a.Value == b.Value
+}
+
+// DiffStructs returns the fields that differ between a and b, values of
+// the same struct type or pointers to it. Every change is Modified, with
+// the field values
+func DiffStructs(a interface{}, b interface{}) ([]Change, error) {
+	old := reflect.Indirect(reflect.ValueOf(a))
+	new := reflect.Indirect(reflect.ValueOf(b))
+
+	if old.Kind() != reflect.Struct || !new.IsValid() || old.Type() != new.Type() {
+		return nil, fmt.Errorf("diff targets should be structs of the same type, got %s and %s", reflect.TypeOf(a), reflect.TypeOf(b))
+	}
+
+	var changes []Change
+
+	diffValues(old, new, "", &changes)
+	return changes, nil
+}
+
+func diffValues(old reflect.Value, new reflect.Value, path string, changes *[]Change) {
+	if old.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			*changes = append(*changes, Change{Path: path, Kind: Modified, Old: old.Interface(), New: new.Interface()})
+		}
+
+		return
+	}
+
+	plan := planOf(old.Type())
+
+	for _, f := range plan.fields {
+		if f.tag == "-" || !f.exported {
+			continue
+		}
+
+		diffValues(old.Field(f.index), new.Field(f.index), joinPath(path, f.tag), changes)
+	}
+}
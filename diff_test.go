@@ -0,0 +1,129 @@
+package cfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffStructs(t *testing.T) {
+	type config struct {
+		Name   string `cfg:"name"`
+		Port   int    `cfg:"port"`
+		Ignore string `cfg:"-"`
+		Voice  struct {
+			BitRate int `cfg:"bitrate"`
+		} `cfg:"voice"`
+		Tags []string `cfg:"tags"`
+	}
+
+	old := config{Name: "server", Port: 80, Ignore: "a", Tags: []string{"a"}}
+	new := old
+	new.Port = 81
+	new.Ignore = "b"
+	new.Voice.BitRate = 64000
+	new.Tags = []string{"a", "b"}
+
+	expected := []Change{
+		{Path: "port", Kind: Modified, Old: 80, New: 81},
+		{Path: "voice.bitrate", Kind: Modified, Old: 0, New: 64000},
+		{Path: "tags", Kind: Modified, Old: []string{"a"}, New: []string{"a", "b"}},
+	}
+
+	changes, err := DiffStructs(&old, &new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+
+	if changes, _ := DiffStructs(old, old); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	if s := changes[0].String(); s != "port modified: 80 -> 81" {
+		t.Fatalf("wrong string, got %q", s)
+	}
+}
+
+func TestDiffStructsErrors(t *testing.T) {
+	for _, c := range []struct {
+		a interface{}
+		b interface{}
+	}{
+		{a: 1, b: 1},
+		{a: struct{}{}, b: nil},
+		{a: struct{ A int }{}, b: struct{ B int }{}},
+	} {
+		_, err := DiffStructs(c.a, c.b)
+		if err == nil {
+			t.Fatalf("expected error for %T and %T", c.a, c.b)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := []byte(`# server
+name: 'server',
+port: 7788
+tags: [a, b]
+voice: {
+  bitrate: 64000
+  quality: 1
+}
+debug: true`)
+
+	new := []byte(`voice: { quality: 1, bitrate: 128000 }
+name: "server"
+tags: [
+  'a',
+  b,
+  c
+]
+port: 7788
+port: 7789
+token: abc`)
+
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Path: "port", Kind: Modified, Old: "7788", New: "7789"},
+		{Path: "tags", Kind: Modified, Old: []interface{}{"a", "b"}, New: []interface{}{"a", "b", "c"}},
+		{Path: "voice.bitrate", Kind: Modified, Old: "64000", New: "128000"},
+		{Path: "debug", Kind: Removed, Old: "true"},
+		{Path: "token", Kind: Added, New: "abc"},
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+
+	changes, err = Diff(old, old)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v, %v", changes, err)
+	}
+
+	// repeated keys are merged like Unmarshal does
+	changes, err = Diff([]byte("voice: { bitrate: 1 }\nvoice: { port: 2 }\ntags: [a]\ntags: [b]"), []byte("voice: { bitrate: 1, port: 2 }\ntags: [a, b]"))
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes for repeated keys, got %v, %v", changes, err)
+	}
+
+	changes, err = Diff([]byte("voice: { bitrate: 1 }\nvoice: { port: 2 }"), []byte("voice: { bitrate: 1 }"))
+
+	expected = []Change{{Path: "voice.port", Kind: Removed, Old: "2"}}
+
+	if err != nil || !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v, %v", expected, changes, err)
+	}
+
+	_, err = Diff(old, []byte("name: [a"))
+	if err == nil || !strings.Contains(err.Error(), "could not parse new config") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+}
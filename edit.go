@@ -0,0 +1,186 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Set sets the value at path, see Lookup, replacing the value of the last
+// entry with the key or adding an entry, and the inner structs leading to
+// it when they are missing. Array elements can be replaced but not added,
+// see Append. The comments of a replaced entry or element are kept
+func (n *Node) Set(path string, value *Node) error {
+	segments := strings.Split(path, ".")
+
+	// checked first so the tree is not changed when the path is not valid
+	for i, segment := range segments {
+		if _, _, ok := splitIndexes(segment); !ok {
+			return &PathError{Path: path, Segment: strings.Join(segments[:i+1], "."), Msg: "is not a valid path"}
+		}
+	}
+
+	current := n
+	resolved := ""
+
+	for i, segment := range segments {
+		key, indexes, _ := splitIndexes(segment)
+		last := i == len(segments)-1
+
+		parent := resolved
+		resolved = joinPath(resolved, key)
+
+		if current.Kind != ObjectNode {
+			return &PathError{Path: path, Segment: resolved, Msg: fmt.Sprintf("not found, %s is not an inner struct", parent)}
+		}
+
+		e := current.entry(key)
+
+		if e == nil {
+			if strings.Contains(strings.Join(segments[i:], "."), "[") {
+				return &PathError{Path: path, Segment: resolved, Msg: "not found"}
+			}
+
+			e = &Entry{Key: key, Value: &Node{Kind: ObjectNode}}
+			current.Entries = append(current.Entries, e)
+		}
+
+		if last && len(indexes) == 0 {
+			e.Value = value
+			return nil
+		}
+
+		current = e.Value
+
+		if len(indexes) == 0 {
+			continue
+		}
+
+		// elements are counted on every array of a repeated key, like Lookup
+		nodes, err := n.resolve(path, append(segments[:i:i], key))
+		if err != nil {
+			return err
+		}
+
+		for j, index := range indexes {
+			array, k, err := element(nodes, path, resolved, index)
+			if err != nil {
+				return err
+			}
+
+			if last && j == len(indexes)-1 {
+				old := array.Items[k]
+				value.Comments, value.Comment = old.Comments, old.Comment
+				array.Items[k] = value
+				return nil
+			}
+
+			nodes = []*Node{array.Items[k]}
+			resolved = fmt.Sprintf("%s[%d]", resolved, index)
+		}
+
+		current = nodes[0]
+	}
+
+	return nil
+}
+
+// Delete removes the value at path, see Lookup, or the array element. Keys
+// are removed from every entry with the key, and from every inner struct
+// of a repeated key on the path, as they are merged when decoded. The
+// comments above them are kept, moved to the entry or element after them
+func (n *Node) Delete(path string) error {
+	segments := strings.Split(path, ".")
+	key, indexes, _ := splitIndexes(segments[len(segments)-1])
+
+	if len(indexes) == 0 {
+		parents, err := n.resolve(path, segments[:len(segments)-1])
+		removed := false
+
+		for _, parent := range parents {
+			if err == nil && parent.Kind == ObjectNode && parent.deleteKey(key) {
+				removed = true
+			}
+		}
+
+		if removed {
+			return nil
+		}
+	}
+
+	_, err := n.Lookup(path)
+	if err != nil || len(indexes) == 0 {
+		return err
+	}
+
+	nodes, _ := n.resolve(path, strings.Split(path[:strings.LastIndex(path, "[")], "."))
+	parent, index, _ := element(nodes, path, "", indexes[len(indexes)-1])
+
+	var comments []string
+
+	items := make([]*Node, 0, len(parent.Items)-1)
+
+	for i, item := range parent.Items {
+		if i == index {
+			comments = item.Comments
+			continue
+		}
+
+		if len(comments) > 0 {
+			item.Comments = append(comments[:len(comments):len(comments)], item.Comments...)
+			comments = nil
+		}
+
+		items = append(items, item)
+	}
+
+	parent.Items = items
+	parent.EndComments = append(comments, parent.EndComments...)
+	return nil
+}
+
+// deleteKey removes every entry with key, moving their comments to the
+// entry after them, and reports whether there was one
+func (n *Node) deleteKey(key string) bool {
+	var comments []string
+
+	removed := false
+	entries := make([]*Entry, 0, len(n.Entries))
+
+	for _, e := range n.Entries {
+		if e.Key == key {
+			comments = append(comments, e.Comments...)
+			removed = true
+			continue
+		}
+
+		if len(comments) > 0 {
+			e.Comments = append(comments, e.Comments...)
+			comments = nil
+		}
+
+		entries = append(entries, e)
+	}
+
+	n.Entries = entries
+	n.EndComments = append(comments, n.EndComments...)
+	return removed
+}
+
+// Append adds value to the end of the array at path, see Lookup, the
+// array is set when path does not exist
+func (n *Node) Append(path string, value *Node) error {
+	nodes, err := n.resolve(path, strings.Split(path, "."))
+	if err != nil {
+		return n.Set(path, &Node{Kind: ArrayNode, Items: []*Node{value}})
+	}
+
+	// the last array of a repeated key, its elements come last when decoded
+	array := nodes[len(nodes)-1]
+
+	if array.Kind != ArrayNode {
+		return &PathError{Path: path, Segment: path, Msg: "is not an array"}
+	}
+
+	array.Items = append(array.Items, value)
+	return nil
+}
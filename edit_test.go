@@ -0,0 +1,239 @@
+package cfg
+
+import (
+	"testing"
+)
+
+const editExample = `# server
+name: 'TestServer'
+# voice settings
+voice: {
+  port: 7788 # the port
+}
+resources: [
+  # core
+  chat,
+  race
+]
+`
+
+func TestNodeEdit(t *testing.T) {
+	scalar := func(value string) *Node {
+		return &Node{Kind: ScalarNode, Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		edit     func(root *Node) error
+		expected string
+		err      string
+	}{
+		{
+			name: "set value",
+			edit: func(root *Node) error {
+				return root.Set("voice.port", scalar("7799"))
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nvoice: {\n  port: 7799 # the port\n}\nresources: [\n  # core\n  chat,\n  race\n]\n",
+		},
+		{
+			name: "set new inner struct",
+			edit: func(root *Node) error {
+				return root.Set("a.b", scalar("c"))
+			},
+			expected: editExample + "a: {\n  b: c\n}\n",
+		},
+		{
+			name: "set array element",
+			edit: func(root *Node) error {
+				return root.Set("resources[0]", scalar("admin"))
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nvoice: {\n  port: 7788 # the port\n}\nresources: [\n  # core\n  admin,\n  race\n]\n",
+		},
+		{
+			name: "set missing array element",
+			edit: func(root *Node) error {
+				return root.Set("resources[2]", scalar("admin"))
+			},
+			err: `could not resolve "resources[2]", resources[2] not found, resources has 2 elements`,
+		},
+		{
+			name: "set missing array",
+			edit: func(root *Node) error {
+				return root.Set("a.b[0]", scalar("admin"))
+			},
+			err: `could not resolve "a.b[0]", a not found`,
+		},
+		{
+			name: "set inside a value",
+			edit: func(root *Node) error {
+				return root.Set("name.a", scalar("b"))
+			},
+			err: `could not resolve "name.a", name.a not found, name is not an inner struct`,
+		},
+		{
+			name: "set invalid path",
+			edit: func(root *Node) error {
+				return root.Set("a..b", scalar("c"))
+			},
+			err: `could not resolve "a..b", a. is not a valid path`,
+		},
+		{
+			name: "delete entry",
+			edit: func(root *Node) error {
+				return root.Delete("voice")
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nresources: [\n  # core\n  chat,\n  race\n]\n",
+		},
+		{
+			name: "delete inner entry",
+			edit: func(root *Node) error {
+				return root.Delete("voice.port")
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nvoice: {}\nresources: [\n  # core\n  chat,\n  race\n]\n",
+		},
+		{
+			name: "delete array element",
+			edit: func(root *Node) error {
+				return root.Delete("resources[0]")
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nvoice: {\n  port: 7788 # the port\n}\nresources: [\n  # core\n  race\n]\n",
+		},
+		{
+			name: "delete missing",
+			edit: func(root *Node) error {
+				return root.Delete("token")
+			},
+			err: `could not resolve "token", token not found`,
+		},
+		{
+			name: "append",
+			edit: func(root *Node) error {
+				return root.Append("resources", scalar("admin"))
+			},
+			expected: "# server\nname: 'TestServer'\n# voice settings\nvoice: {\n  port: 7788 # the port\n}\nresources: [\n  # core\n  chat,\n  race,\n  admin\n]\n",
+		},
+		{
+			name: "append new array",
+			edit: func(root *Node) error {
+				return root.Append("modules", scalar("js-module"))
+			},
+			expected: editExample + "modules: [\n  js-module\n]\n",
+		},
+		{
+			name: "append to a value",
+			edit: func(root *Node) error {
+				return root.Append("name", scalar("x"))
+			},
+			err: `could not resolve "name", name is not an array`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := ParseDocument([]byte(editExample))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = test.edit(root)
+
+			if len(test.err) > 0 {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+
+				if got := string(FormatNode(root)); got != editExample {
+					t.Fatalf("expected the document to be unchanged, got\n%s", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := string(FormatNode(root)); got != test.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestNodeDeleteRepeated(t *testing.T) {
+	root, err := ParseDocument([]byte("# first\na: 1\nb: 2\n# second\na: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.Delete("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# first\nb: 2\n# second\n"
+
+	if got := string(FormatNode(root)); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestNodeDeleteRepeatedParents(t *testing.T) {
+	root, err := ParseDocument([]byte("a: { b: 1 }\nc: 2\na: { b: 2, d: 3 }\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.Delete("a.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		A struct {
+			B int `cfg:"b"`
+			D int `cfg:"d"`
+		} `cfg:"a"`
+	}
+
+	err = Unmarshal(FormatNode(root), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.A.B != 0 || v.A.D != 3 {
+		t.Fatalf("expected a.b to be deleted from every a, got %+v\n%s", v, FormatNode(root))
+	}
+
+	if err := root.Delete("a.b"); err == nil {
+		t.Fatalf("expected an error deleting a.b again")
+	}
+}
+
+func TestNodeEditRepeatedArrays(t *testing.T) {
+	root, err := ParseDocument([]byte("a: [x]\nb: 1\na: [y]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.Set("a[1]", &Node{Kind: ScalarNode, Value: "z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.Append("a", &Node{Kind: ScalarNode, Value: "w"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = root.Delete("a[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a: []\nb: 1\na: [\n  z,\n  w\n]\n"
+
+	if got := string(FormatNode(root)); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,153 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ApplyEnv overrides the fields of the struct pointed by v with environment
+// variables, resolved through lookup or os.LookupEnv when lookup is nil.
+//
+// A field is overridden by the variable named on its env tag, like
+// `env:"ALTV_PORT"`. When prefix is not empty, fields without an env tag are
+// overridden by the variable named after the prefix and the key path,
+// like ALTV_VOICE_EXTERNALPORT for voice.externalPort with the prefix ALTV.
+// The tag `env:"-"` disables the override of a field.
+//
+// Values are converted like the ones decoded by Unmarshal, slices are
+// read as a comma separated list.
+func ApplyEnv(v interface{}, prefix string, lookup LookupFunc) error {
+	return applyEnvOverrides(v, prefix, lookup, nil)
+}
+
+// applyEnvOverrides works like ApplyEnv, recording the fields overridden
+// as set on md when it is not nil
+func applyEnvOverrides(v interface{}, prefix string, lookup LookupFunc, md *Metadata) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env target should be a pointer to a struct, got %s", reflect.TypeOf(v))
+	}
+
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	return applyEnv(rv.Elem(), "", prefix, lookup, md)
+}
+
+func applyEnv(rv reflect.Value, path string, prefix string, lookup LookupFunc, md *Metadata) error {
+	plan := planOf(rv.Type())
+
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		if fp.tag == "-" || !fp.exported || fp.env == "-" {
+			continue
+		}
+
+		fieldPath := joinPath(path, fp.tag)
+		f := fp.field(rv)
+
+		if fp.isInner {
+			err := applyEnv(f.Value, fieldPath, prefix, lookup, md)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := fp.env
+
+		if len(name) == 0 {
+			if len(prefix) == 0 {
+				continue
+			}
+
+			name = envName(prefix, fieldPath)
+		}
+
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		err := setStringValue(f, value)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not apply environment variable %s to %s", name, fieldPath))
+		}
+
+		if md != nil {
+			md.markSet(fieldPath)
+		}
+	}
+
+	return nil
+}
+
+// envName returns the automatic variable name of a key path
+func envName(prefix string, path string) string {
+	return strings.ToUpper(prefix + "_" + strings.Replace(path, ".", "_", -1))
+}
+
+// setStringValue sets a value read from outside of a CFG input, like an
+// environment variable or a flag, slices are read as a comma separated list
+func setStringValue(f field, value string) error {
+	if !f.IsArray {
+		err := checkNumber(f.Value.Type(), value)
+		if err != nil {
+			return err
+		}
+
+		return setValue(f, value)
+	}
+
+	f.Value.Set(reflect.MakeSlice(f.Value.Type(), 0, 0))
+
+	if len(strings.TrimSpace(value)) == 0 {
+		return nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+
+		err := checkNumber(f.Value.Type().Elem(), item)
+		if err == nil {
+			err = setSliceValue(f, item)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNumber returns an error when value is not a number of the type t,
+// nil for other types. Unlike CFG inputs, which decode invalid numbers as
+// zero, values set by operators must be valid
+func checkNumber(t reflect.Type, value string) error {
+	var err error
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = strconv.ParseInt(value, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(value, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(value, t.Bits())
+	}
+
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, t.Kind()))
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package cfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type envTarget struct {
+	Name     string   `cfg:"name"`
+	Port     int      `cfg:"port" env:"ALTV_PORT"`
+	Debug    bool     `cfg:"debug"`
+	Token    string   `cfg:"token" env:"-"`
+	Ignored  string   `cfg:"-"`
+	Tags     []string `cfg:"tags"`
+	Ports    []uint16 `cfg:"ports" env:"ALTV_PORTS"`
+	internal string
+	Voice    struct {
+		ExternalPort int `cfg:"externalPort"`
+	} `cfg:"voice"`
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"ALTV_PORT":               "8000",
+		"ALTV_NAME":               "from-env",
+		"ALTV_DEBUG":              "true",
+		"ALTV_TOKEN":              "not-used",
+		"ALTV_TAGS":               "a, b,c",
+		"ALTV_PORTS":              "1,2",
+		"ALTV_VOICE_EXTERNALPORT": "7799",
+	}
+
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	t.Run("tags only", func(t *testing.T) {
+		v := envTarget{Name: "file", Tags: []string{"x"}}
+
+		err := ApplyEnv(&v, "", lookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Port != 8000 || !reflect.DeepEqual(v.Ports, []uint16{1, 2}) {
+			t.Fatalf("expected tagged fields to be overridden, got %+v", v)
+		}
+
+		if v.Name != "file" || v.Debug || !reflect.DeepEqual(v.Tags, []string{"x"}) {
+			t.Fatalf("expected untagged fields to be kept, got %+v", v)
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		v := envTarget{Tags: []string{"x"}}
+
+		err := ApplyEnv(&v, "ALTV", lookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Name != "from-env" || !v.Debug || v.Port != 8000 || v.Voice.ExternalPort != 7799 {
+			t.Fatalf("wrong value overridden, got %+v", v)
+		}
+
+		if !reflect.DeepEqual(v.Tags, []string{"a", "b", "c"}) {
+			t.Fatalf("wrong slice overridden, got %v", v.Tags)
+		}
+
+		if v.Token != "" {
+			t.Fatalf("expected token to not be overridden, got %q", v.Token)
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		v := envTarget{Port: 7788}
+
+		for name, value := range map[string]string{"ALTV_PORT": "nope", "ALTV_PORTS": "1,x"} {
+			err := ApplyEnv(&v, "", func(key string) (string, bool) {
+				return value, key == name
+			})
+			if err == nil || !strings.Contains(err.Error(), "could not apply environment variable "+name) {
+				t.Fatalf("expected an error for %s=%s, got %v", name, value, err)
+			}
+		}
+
+		if v.Port != 7788 {
+			t.Fatalf("expected the port to be kept, got %d", v.Port)
+		}
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		if err := ApplyEnv(envTarget{}, "", lookup); err == nil {
+			t.Fatal("expected error for non-pointer target")
+		}
+	})
+}
+
+func TestDecoderEnvOverrides(t *testing.T) {
+	var v envTarget
+
+	dec := NewDecoder(strings.NewReader("port: 7788\nname: file"))
+	dec.EnvOverrides("", func(name string) (string, bool) {
+		if name == "ALTV_PORT" {
+			return "8000", true
+		}
+
+		return "", false
+	})
+
+	err := dec.Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Port != 8000 || v.Name != "file" {
+		t.Fatalf("wrong value decoded, got %+v", v)
+	}
+}
+
+func TestDecoderEnvOverridesMetadata(t *testing.T) {
+	var v envTarget
+
+	dec := NewDecoder(strings.NewReader("name: file"))
+	dec.EnvOverrides("", func(name string) (string, bool) {
+		return "8000", name == "ALTV_PORT"
+	})
+
+	err := dec.Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"debug", "token", "tags", "ports", "voice.externalPort"}
+
+	if unset := dec.Metadata().Unset(); !reflect.DeepEqual(unset, expected) {
+		t.Fatalf("expected unset fields %v, got %v", expected, unset)
+	}
+}
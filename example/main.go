@@ -2,46 +2,52 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 
 	"github.com/crossworth/cfg"
 )
 
-func main() {
-	example := struct {
-		Name         string   `cfg:"name"`
-		Host         string   `cfg:"host"`
-		Port         int      `cfg:"port"`
-		Players      int      `cfg:"players"`
-		Announce     bool     `cfg:"announce"`
-		GameMode     string   `cfg:"gamemode"`
-		WebSite      string   `cfg:"website"`
-		Language     string   `cfg:"language"`
-		Description  string   `cfg:"description"`
-		Debug        bool     `cfg:"debug"`
-		UseEarlyAuth bool     `cfg:"useEarlyAuth"`
-		EarlyAuthURL string   `cfg:"earlyAuthUrl"`
-		UseCDN       bool     `cfg:"useCdn"`
-		CDNUrl       string   `cfg:"cdnUrl"`
-		Modules      []string `cfg:"modules"`
-		Resources    []string `cfg:"resources"`
-		Tags         []string `cfg:"tags"`
-		Voice        struct {
-			BitRate            int    `cfg:"bitrate"`
-			ExternalHost       string `cfg:"externalHost"`
-			ExternalPort       int    `cfg:"externalPort"`
-			ExternalPublicHost string `cfg:"externalPublicHost"`
-			ExternalPublicPort int    `cfg:"externalPublicPort"`
-		} `cfg:"voice"`
-	}{}
-
-	data, err := ioutil.ReadFile("./example/example.cfg")
-	if err != nil {
-		log.Fatal(err)
+type config struct {
+	Name         string   `cfg:"name"`
+	Host         string   `cfg:"host"`
+	Port         int      `cfg:"port" validate:"port"`
+	Players      int      `cfg:"players" validate:"min=1,max=4096"`
+	Announce     bool     `cfg:"announce"`
+	Token        string   `cfg:"token" validate:"required_if=announce true"`
+	GameMode     string   `cfg:"gamemode"`
+	WebSite      string   `cfg:"website"`
+	Language     string   `cfg:"language"`
+	Description  string   `cfg:"description"`
+	Debug        bool     `cfg:"debug"`
+	UseEarlyAuth bool     `cfg:"useEarlyAuth"`
+	EarlyAuthURL string   `cfg:"earlyAuthUrl"`
+	UseCDN       bool     `cfg:"useCdn"`
+	CDNUrl       string   `cfg:"cdnUrl" validate:"required_if=useCdn true,url"`
+	Modules      []string `cfg:"modules"`
+	Resources    []string `cfg:"resources"`
+	Tags         []string `cfg:"tags"`
+	Voice        struct {
+		BitRate            int    `cfg:"bitrate"`
+		ExternalHost       string `cfg:"externalHost"`
+		ExternalPort       int    `cfg:"externalPort"`
+		ExternalPublicHost string `cfg:"externalPublicHost" validate:"hostname"`
+		ExternalPublicPort int    `cfg:"externalPublicPort"`
+	} `cfg:"voice"`
+}
+
+// Validate checks the rules that depend on more than one field
+func (c *config) Validate() error {
+	if c.Voice.ExternalPort == c.Port {
+		return &cfg.ValidationError{Path: "voice.externalPort", Msg: "must differ from port"}
 	}
 
-	err = cfg.Unmarshal(data, &example)
+	return nil
+}
+
+func main() {
+	var example config
+
+	err := cfg.LoadFile("./example/example.cfg", &example)
 	if err != nil {
 		log.Fatal(err)
 	}
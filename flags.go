@@ -0,0 +1,136 @@
+package cfg
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// flagValue is a flag.Value bound to a struct field
+type flagValue struct {
+	root  interface{}
+	field field
+	// value is the last value set from the command line
+	value string
+	set   bool
+}
+
+func (fv *flagValue) String() string {
+	// the flag package calls String on zero values to detect defaults
+	if fv == nil || !fv.field.Value.IsValid() {
+		return ""
+	}
+
+	if fv.field.IsArray {
+		items := make([]string, 0, fv.field.Value.Len())
+
+		for i := 0; i < fv.field.Value.Len(); i++ {
+			items = append(items, fmt.Sprint(fv.field.Value.Index(i).Interface()))
+		}
+
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(fv.field.Value.Interface())
+}
+
+func (fv *flagValue) Set(value string) error {
+	err := setStringValue(fv.field, value)
+	if err != nil {
+		return err
+	}
+
+	fv.value = value
+	fv.set = true
+	return nil
+}
+
+// IsBoolFlag allows bool flags to be set without a value, like -debug
+func (fv *flagValue) IsBoolFlag() bool {
+	return fv.field.Kind == reflect.Bool
+}
+
+// apply sets the field again with the value from the command line
+func (fv *flagValue) apply() error {
+	if !fv.set {
+		return nil
+	}
+
+	return setStringValue(fv.field, fv.value)
+}
+
+// BindFlags registers a flag on fs for every field of the struct pointed by
+// v, named after the key path of the field, like -port or -voice.bitrate.
+// The current value of each field is used as the flag default. Slices are
+// read as a comma separated list. Invalid numbers fail the parsing instead
+// of setting zero like CFG inputs do.
+//
+// Parsing fs sets the fields right away, Load applies the flags again
+// after the file and the environment so they take precedence
+func BindFlags(fs *flag.FlagSet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("flag target should be a pointer to a struct, got %s", reflect.TypeOf(v))
+	}
+
+	return bindFlags(fs, v, rv.Elem(), "")
+}
+
+func bindFlags(fs *flag.FlagSet, root interface{}, rv reflect.Value, path string) error {
+	plan := planOf(rv.Type())
+
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		if fp.tag == "-" || !fp.exported {
+			continue
+		}
+
+		fieldPath := joinPath(path, fp.tag)
+		f := fp.field(rv)
+
+		if fp.isInner {
+			err := bindFlags(fs, root, f.Value, fieldPath)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if fp.set == nil {
+			continue
+		}
+
+		if fs.Lookup(fieldPath) != nil {
+			return fmt.Errorf("flag %s is already defined", fieldPath)
+		}
+
+		fs.Var(&flagValue{root: root, field: f}, fieldPath, fmt.Sprintf("sets %s", fieldPath))
+	}
+
+	return nil
+}
+
+// applyFlags sets again the fields of v from the flags explicitly set on fs
+func applyFlags(fs *flag.FlagSet, v interface{}) error {
+	var err error
+
+	fs.Visit(func(f *flag.Flag) {
+		fv, ok := f.Value.(*flagValue)
+		if !ok || err != nil {
+			return
+		}
+
+		if fv.root != v {
+			err = fmt.Errorf("flag %s is bound to a different value", f.Name)
+			return
+		}
+
+		err = fv.apply()
+	})
+
+	return err
+}
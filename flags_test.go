@@ -0,0 +1,103 @@
+package cfg
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type flagTarget struct {
+	Name    string   `cfg:"name"`
+	Port    int      `cfg:"port"`
+	Debug   bool     `cfg:"debug"`
+	Tags    []string `cfg:"tags"`
+	Ignored string   `cfg:"-"`
+	Other   map[string]string
+	Voice   struct {
+		BitRate int `cfg:"bitrate"`
+	} `cfg:"voice"`
+}
+
+func TestBindFlags(t *testing.T) {
+	v := flagTarget{Name: "default", Port: 7788, Tags: []string{"a", "b"}}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	err := BindFlags(fs, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+
+	if !reflect.DeepEqual(names, []string{"debug", "name", "port", "tags", "voice.bitrate"}) {
+		t.Fatalf("wrong flags registered, got %v", names)
+	}
+
+	if fs.Lookup("port").DefValue != "7788" || fs.Lookup("tags").DefValue != "a,b" {
+		t.Fatal("expected current values as defaults")
+	}
+
+	err = fs.Parse([]string{"-debug", "-voice.bitrate", "128000", "-tags", "x,y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !v.Debug || v.Voice.BitRate != 128000 || !reflect.DeepEqual(v.Tags, []string{"x", "y"}) {
+		t.Fatalf("wrong value set, got %+v", v)
+	}
+
+	if v.Name != "default" || v.Port != 7788 {
+		t.Fatalf("expected flags not set to keep the defaults, got %+v", v)
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		var out bytes.Buffer
+		fs.SetOutput(&out)
+		fs.PrintDefaults()
+
+		if !strings.Contains(out.String(), "-voice.bitrate") {
+			t.Fatalf("expected usage to list voice.bitrate, got %q", out.String())
+		}
+	})
+
+	t.Run("duplicated", func(t *testing.T) {
+		if err := BindFlags(fs, &v); err == nil {
+			t.Fatal("expected error binding the same flags twice")
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		for _, args := range [][]string{{"-port", "abc"}, {"-voice.bitrate", "1.5"}} {
+			v := flagTarget{Port: 7788}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+
+			err := BindFlags(fs, &v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fs.Parse(args); err == nil || !strings.Contains(err.Error(), "could not convert") {
+				t.Fatalf("expected an error for %v, got %v", args, err)
+			}
+
+			if v.Port != 7788 || v.Voice.BitRate != 0 {
+				t.Fatalf("expected the values to be kept, got %+v", v)
+			}
+		}
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		if err := BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), v); err == nil {
+			t.Fatal("expected error for non-pointer target")
+		}
+	})
+}
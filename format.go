@@ -0,0 +1,205 @@
+package cfg
+
+import (
+	"bytes"
+	"strings"
+)
+
+// indentUnit is the indentation of each level of arrays and inner structs
+const indentUnit = "  "
+
+// Format returns data in the canonical format: one key or array element per
+// line indented by two spaces, no commas between keys, single quotes unless
+// the value contains one, at most one blank line between keys and trailing
+// comments aligned. Comments, including commented out keys, are kept
+func Format(data []byte) ([]byte, error) {
+	root, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatNode(root), nil
+}
+
+// FormatNode returns the canonical format of the root node, as returned by
+// ParseDocument and possibly modified, see Format
+func FormatNode(root *Node) []byte {
+	var p printer
+
+	p.entries(root, 0)
+	p.comments(root.EndComments, 0, len(root.Entries) == 0, true)
+
+	return p.bytes()
+}
+
+// line is an output line, comment is aligned with the comments of the
+// lines around it when align is set
+type line struct {
+	indent  int
+	text    string
+	comment string
+	align   bool
+}
+
+type printer struct {
+	lines []line
+}
+
+func (p *printer) add(l line) {
+	p.lines = append(p.lines, l)
+}
+
+func (p *printer) entries(n *Node, indent int) {
+	for i, e := range n.Entries {
+		p.comments(e.Comments, indent, i == 0, false)
+		p.value(formatKey(e.Key)+": ", e.Value, "", e.Comment, indent)
+	}
+}
+
+// comments adds comment lines, blank lines are collapsed and dropped at
+// the start of a block, and at the end when last is set
+func (p *printer) comments(lines []string, indent int, first bool, last bool) {
+	blank := false
+	emitted := false
+
+	for _, comment := range lines {
+		if len(comment) == 0 {
+			blank = true
+			continue
+		}
+
+		if blank && (!first || emitted) {
+			p.add(line{})
+		}
+
+		p.add(line{indent: indent, text: comment})
+
+		blank = false
+		emitted = true
+	}
+
+	if blank && !last && (!first || emitted) {
+		p.add(line{})
+	}
+}
+
+// value adds the lines of n, starting with prefix and ending with suffix
+func (p *printer) value(prefix string, n *Node, suffix string, comment string, indent int) {
+	open, close := "[", "]"
+
+	switch n.Kind {
+	case ScalarNode:
+		p.add(line{indent: indent, text: prefix + formatScalar(n) + suffix, comment: comment, align: true})
+		return
+	case ObjectNode:
+		open, close = "{", "}"
+
+		if len(n.Entries) == 0 && len(n.OpenComment) == 0 && !hasComments(n.EndComments) {
+			p.add(line{indent: indent, text: prefix + open + close + suffix, comment: comment, align: true})
+			return
+		}
+
+		p.add(line{indent: indent, text: prefix + open, comment: n.OpenComment})
+		p.entries(n, indent+1)
+	case ArrayNode:
+		if len(n.Items) == 0 && len(n.OpenComment) == 0 && !hasComments(n.EndComments) {
+			p.add(line{indent: indent, text: prefix + open + close + suffix, comment: comment, align: true})
+			return
+		}
+
+		p.add(line{indent: indent, text: prefix + open, comment: n.OpenComment})
+
+		for i, item := range n.Items {
+			separator := ","
+
+			if i == len(n.Items)-1 {
+				separator = ""
+			}
+
+			p.comments(item.Comments, indent+1, i == 0, false)
+			p.value("", item, separator, item.Comment, indent+1)
+		}
+	}
+
+	empty := len(n.Entries) == 0 && len(n.Items) == 0
+	p.comments(n.EndComments, indent+1, empty, true)
+	p.add(line{indent: indent, text: close + suffix, comment: comment})
+}
+
+// bytes returns the lines with the comments of consecutive single line
+// values at the same indentation aligned
+func (p *printer) bytes() []byte {
+	var buf bytes.Buffer
+
+	for start := 0; start < len(p.lines); {
+		end := start + 1
+
+		for p.lines[start].align && end < len(p.lines) && p.lines[end].align && p.lines[end].indent == p.lines[start].indent {
+			end++
+		}
+
+		width := 0
+
+		for _, l := range p.lines[start:end] {
+			if len(l.comment) > 0 && len(l.text) > width {
+				width = len(l.text)
+			}
+		}
+
+		for _, l := range p.lines[start:end] {
+			if len(l.text) > 0 {
+				buf.WriteString(strings.Repeat(indentUnit, l.indent))
+				buf.WriteString(l.text)
+			}
+
+			if len(l.comment) > 0 {
+				buf.WriteString(strings.Repeat(" ", width-len(l.text)+1))
+				buf.WriteString(l.comment)
+			}
+
+			buf.WriteByte('\n')
+		}
+
+		start = end
+	}
+
+	return buf.Bytes()
+}
+
+func hasComments(lines []string) bool {
+	for _, l := range lines {
+		if len(l) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatScalar returns the value of n with single quotes, or double quotes
+// when it contains a single quote, unquoted values stay unquoted
+func formatScalar(n *Node) string {
+	if n.Quote == 0 && len(n.Value) > 0 {
+		return n.Value
+	}
+
+	return quote(n.Value)
+}
+
+// formatKey returns key, quoted when it can't be written unquoted
+func formatKey(key string) string {
+	if len(key) == 0 || strings.Trim(key, spaces) != key || strings.ContainsAny(key, ":\n#,[]{}") ||
+		key[0] == '\'' || key[0] == '"' {
+		return quote(key)
+	}
+
+	return key
+}
+
+func quote(s string) string {
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+
+	return "'" + s + "'"
+}
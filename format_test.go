@@ -0,0 +1,136 @@
+package cfg
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "separators and quotes",
+			input: `name: "TestServer", host: 0.0.0.0,
+quoted: "it's",
+'a key': 1
+empty: ,
+'a:b': c`,
+			expected: `name: 'TestServer'
+host: 0.0.0.0
+quoted: "it's"
+a key: 1
+empty: ''
+'a:b': c
+`,
+		},
+		{
+			name: "comments",
+			input: `# server config
+
+
+port: 7788 # the port
+players: 1024   # max players
+#password: "verysecurepassword", # remove hashtag before password to enable
+announce: false, # set to false during development
+
+# voice
+
+
+voice: { # voice settings
+  bitrate: 64000
+  #externalSecret: 3499211612
+} # end voice
+key:
+  # value below
+  value
+# trailing comment
+
+
+`,
+			expected: `# server config
+
+port: 7788    # the port
+players: 1024 # max players
+#password: "verysecurepassword", # remove hashtag before password to enable
+announce: false # set to false during development
+
+# voice
+
+voice: { # voice settings
+  bitrate: 64000
+  #externalSecret: 3499211612
+} # end voice
+# value below
+key: value
+# trailing comment
+`,
+		},
+		{
+			name: "arrays and inner structs",
+			input: `modules: [ "node-module", # js
+  'csharp-module'
+  # more later
+],
+empty: [], inner: {}
+nested: {
+    a: { b: [x, y] }
+}`,
+			expected: `modules: [
+  'node-module', # js
+  'csharp-module'
+  # more later
+]
+empty: []
+inner: {}
+nested: {
+  a: {
+    b: [
+      x,
+      y
+    ]
+  }
+}
+`,
+		},
+		{
+			name:     "only comments",
+			input:    "\n\n# a\n\n\n# b\n\n",
+			expected: "# a\n\n# b\n",
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := Format([]byte(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != c.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", c.expected, out)
+			}
+
+			again, err := Format(out)
+			if err != nil || string(again) != string(out) {
+				t.Fatalf("expected formatting to be stable, got\n%s", again)
+			}
+
+			changes, err := Diff([]byte(c.input), out)
+			if err != nil || len(changes) > 0 {
+				t.Fatalf("expected the same values, got %v, %v", changes, err)
+			}
+		})
+	}
+
+	_, err := Format([]byte("name: [a"))
+	if err == nil {
+		t.Fatal("expected syntax error")
+	}
+}
@@ -0,0 +1,63 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FS is the file system config files are read from
+type FS interface {
+	// ReadFile returns the contents of the file name
+	ReadFile(name string) ([]byte, error)
+	// Glob returns the names of the files matching pattern, sorted,
+	// with the syntax of filepath.Match
+	Glob(pattern string) ([]string, error)
+}
+
+// OSFS is the FS of the operating system
+type OSFS struct{}
+
+// ReadFile implements FS
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// Glob implements FS
+func (OSFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// MapFS is an in memory FS, mapping file names to their contents,
+// useful for tests
+type MapFS map[string][]byte
+
+// ReadFile implements FS
+func (m MapFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	return data, nil
+}
+
+// Glob implements FS
+func (m MapFS) Glob(pattern string) ([]string, error) {
+	// validate the pattern even when there are no files
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+
+	for name := range m {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
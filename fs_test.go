@@ -0,0 +1,71 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMapFS(t *testing.T) {
+	fs := MapFS{
+		"server.cfg":        []byte("name: test"),
+		"conf.d/b.cfg":      []byte("b: 1"),
+		"conf.d/a.cfg":      []byte("a: 1"),
+		"conf.d/readme.txt": []byte("text"),
+	}
+
+	data, err := fs.ReadFile("./server.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "name: test" {
+		t.Fatalf("wrong file read, got %q", data)
+	}
+
+	_, err = fs.ReadFile("missing.cfg")
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+
+	matches, err := fs.Glob("conf.d/*.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(matches, []string{"conf.d/a.cfg", "conf.d/b.cfg"}) {
+		t.Fatalf("wrong matches, got %v", matches)
+	}
+
+	if _, err := fs.Glob("conf.d/[.cfg"); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestOSFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "server.cfg"), []byte("name: test"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fs OSFS
+
+	data, err := fs.ReadFile(filepath.Join(dir, "server.cfg"))
+	if err != nil || string(data) != "name: test" {
+		t.Fatalf("wrong file read, got %q and %v", data, err)
+	}
+
+	matches, err := fs.Glob(filepath.Join(dir, "*.cfg"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("wrong matches, got %v and %v", matches, err)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build go1.18
+// +build go1.18
+
+package cfg
+
+import (
+	"testing"
+)
+
+type fuzzTarget struct {
+	Name     string    `cfg:"name"`
+	Port     int       `cfg:"port"`
+	Uint     uint8     `cfg:"uint"`
+	Float    float32   `cfg:"float"`
+	Debug    bool      `cfg:"debug"`
+	Modules  []string  `cfg:"modules"`
+	Ints     []int     `cfg:"ints"`
+	Uints    []uint16  `cfg:"uints"`
+	Floats   []float64 `cfg:"floats"`
+	Bools    []bool    `cfg:"bools"`
+	Ignored  string    `cfg:"-"`
+	internal string
+	Voice    struct {
+		BitRate int      `cfg:"bitrate"`
+		Host    string   `cfg:"host"`
+		Tags    []string `cfg:"tags"`
+		Inner   struct {
+			Value string `cfg:"value"`
+		} `cfg:"inner"`
+	} `cfg:"voice"`
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	seeds := []string{
+		completeExample,
+		"",
+		"name: ''",
+		"name: '",
+		"name: \"",
+		"name:",
+		"name: ,",
+		"modules: [,]",
+		"modules: [\n,\n]",
+		"modules: ][",
+		"ints: [1, 2, 3]",
+		"floats: [1.5, 2.5]",
+		"uints: [1\n2\n]",
+		"bools: [true, no]",
+		"voice: {}",
+		"voice: }{",
+		"voice: {\n inner: {\n value: x\n }\n}",
+		"internal: secret",
+		"#only a comment",
+		":",
+		"::",
+		"[",
+		"{",
+	}
+
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v fuzzTarget
+		_ = Unmarshal(data, &v)
+	})
+}
+
+func FuzzFormat(f *testing.F) {
+	seeds := []string{
+		completeExample,
+		"",
+		"# comment\n\nname: 'a' # trailing\n",
+		"a: [ # open\n  1, # one\n  # end\n]",
+		"a: { # open\n  b: c\n} # close",
+		"a:\n  # between\n  b",
+		"'a key': \"it's\"",
+		"a: ,",
+		"0:\v\"",
+	}
+
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := Format(data)
+		if err != nil {
+			return
+		}
+
+		again, err := Format(out)
+		if err != nil {
+			t.Fatalf("could not parse formatted output %q of %q: %s", out, data, err)
+		}
+
+		if string(again) != string(out) {
+			t.Fatalf("format is not idempotent for %q, got %q and %q", data, out, again)
+		}
+
+		changes, err := Diff(data, out)
+		if err != nil || len(changes) > 0 {
+			t.Fatalf("format changed the values of %q, got %v %v", data, changes, err)
+		}
+	})
+}
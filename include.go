@@ -0,0 +1,207 @@
+package cfg
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// includeKey is the top level key listing the files to include when
+// loading files, like include: ["resources.cfg", "conf.d/*.cfg"]
+const includeKey = "include"
+
+// FileError is returned when a config file, or a file it includes, can't be loaded
+type FileError struct {
+	// Chain lists the files from the one loaded to the one that failed,
+	// following the includes
+	Chain []string
+	Err   error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %s", strings.Join(e.Chain, " -> "), e.Err)
+}
+
+// Cause returns the underlying error, used by errors.Cause
+func (e *FileError) Cause() error {
+	return e.Err
+}
+
+// loader decodes files into a struct, following their includes
+type loader struct {
+	fs    FS
+	d     decodeState
+	chain []string
+	// files lists every file read, in order
+	files []string
+	// keys counts the keys of every file read, limited by MaxKeys
+	keys int
+}
+
+func newLoader(fs FS, d decodeState) *loader {
+	if fs == nil {
+		fs = OSFS{}
+	}
+
+	return &loader{fs: fs, d: d}
+}
+
+// loadFiles decodes the files in order into the struct pointed by v
+func (l *loader) loadFiles(v interface{}, names ...string) error {
+	rv, err := decodeTarget(v)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		// errors of the Validate method of v are reported on the last file
+		if l.d.md != nil {
+			l.d.md.sources[""] = name
+		}
+
+		err := l.load(name, rv)
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.d.md != nil {
+		l.d.md.finish(v)
+	}
+
+	return nil
+}
+
+func (l *loader) load(name string, rv reflect.Value) error {
+	chain := append(l.chain[:len(l.chain):len(l.chain)], name)
+
+	for _, previous := range l.chain {
+		if previous == name {
+			return &FileError{Chain: chain, Err: errors.New("include cycle")}
+		}
+	}
+
+	data, err := l.fs.ReadFile(name)
+	if err != nil {
+		return &FileError{Chain: chain, Err: err}
+	}
+
+	l.files = append(l.files, name)
+
+	if max := l.d.limits.MaxBytes; max > 0 && int64(len(data)) > max {
+		return &FileError{Chain: chain, Err: &LimitError{Limit: "MaxBytes", Max: max}}
+	}
+
+	root, err := parse(data, l.d.limits)
+	if err != nil {
+		return &FileError{Chain: chain, Err: err}
+	}
+
+	err = l.countKeys(root)
+	if err != nil {
+		return &FileError{Chain: chain, Err: err}
+	}
+
+	parent := l.chain
+	l.chain = chain
+
+	defer func() {
+		l.chain = parent
+	}()
+
+	plan := planOf(rv.Type())
+
+	for _, e := range root.Entries {
+		if e.Key == includeKey {
+			err := l.include(name, e, rv)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if l.d.md != nil {
+			l.d.md.source = name
+		}
+
+		err := l.d.entry(e, rv, plan, "")
+		if err != nil {
+			return &FileError{Chain: chain, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// countKeys adds the keys of n to the keys of the files read before, so
+// MaxKeys limits the keys of all the files together
+func (l *loader) countKeys(n *Node) error {
+	for _, item := range n.Items {
+		err := l.countKeys(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range n.Entries {
+		l.keys++
+
+		if max := l.d.limits.MaxKeys; max > 0 && l.keys > max {
+			return &LimitError{Limit: "MaxKeys", Max: int64(max), Line: e.Pos.Line}
+		}
+
+		err := l.countKeys(e.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// include loads the files listed by an include entry of the file name,
+// relative paths are relative to the directory of name
+func (l *loader) include(name string, e *Entry, rv reflect.Value) error {
+	patterns := []*Node{e.Value}
+
+	if e.Value.Kind == ArrayNode {
+		patterns = e.Value.Items
+	}
+
+	for _, pattern := range patterns {
+		if pattern.Kind != ScalarNode {
+			err := &SyntaxError{Msg: "include expects a file name or an array of file names", Pos: pattern.Pos}
+			return &FileError{Chain: l.chain, Err: err}
+		}
+
+		path := pattern.Value
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(name), path)
+		}
+
+		names := []string{path}
+
+		if strings.ContainsAny(path, "*?[") {
+			matches, err := l.fs.Glob(path)
+			if err != nil {
+				return &FileError{Chain: l.chain, Err: errors.Wrap(err, fmt.Sprintf("could not expand include %q on line %d", pattern.Value, pattern.Pos.Line))}
+			}
+
+			names = matches
+		}
+
+		for _, included := range names {
+			err := l.load(included, rv)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package cfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestInclude(t *testing.T) {
+	type target struct {
+		Name      string   `cfg:"name"`
+		Port      int      `cfg:"port"`
+		Resources []string `cfg:"resources,merge=append"`
+	}
+
+	t.Run("relative paths and globs", func(t *testing.T) {
+		fs := MapFS{
+			"srv/server.cfg": []byte(`name: server
+port: 7788
+include: ["resources.cfg", "conf.d/*.cfg"]
+resources: [last]`),
+			"srv/resources.cfg":   []byte("resources: [chat]"),
+			"srv/conf.d/b.cfg":    []byte("resources: [b]\nport: 80"),
+			"srv/conf.d/a.cfg":    []byte("resources: [a]\ninclude: ../shared/c.cfg"),
+			"srv/shared/c.cfg":    []byte("resources: [c]"),
+			"srv/conf.d/skip.txt": []byte("resources: [skip]"),
+		}
+
+		var v target
+
+		md, err := LoadFilesFS(fs, &v, "srv/server.cfg")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v.Resources, []string{"chat", "a", "c", "b", "last"}) {
+			t.Fatalf("wrong include order, got %v", v.Resources)
+		}
+
+		if v.Port != 80 || md.Source("port") != "srv/conf.d/b.cfg" {
+			t.Fatalf("expected port from srv/conf.d/b.cfg, got %d from %q", v.Port, md.Source("port"))
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		fs := MapFS{
+			"a.cfg": []byte("include: b.cfg"),
+			"b.cfg": []byte("include: [c.cfg]"),
+			"c.cfg": []byte("include: a.cfg"),
+		}
+
+		var v target
+
+		_, err := LoadFilesFS(fs, &v, "a.cfg")
+
+		fileErr, ok := err.(*FileError)
+		if !ok {
+			t.Fatalf("expected *FileError, got %v", err)
+		}
+
+		if !reflect.DeepEqual(fileErr.Chain, []string{"a.cfg", "b.cfg", "c.cfg", "a.cfg"}) {
+			t.Fatalf("wrong include chain, got %v", fileErr.Chain)
+		}
+	})
+
+	t.Run("errors show the chain", func(t *testing.T) {
+		fs := MapFS{
+			"server.cfg":     []byte("include: conf/ports.cfg"),
+			"conf/ports.cfg": []byte("port: 1\nport: [2]"),
+		}
+
+		var v target
+
+		_, err := LoadFilesFS(fs, &v, "server.cfg")
+		if err == nil || !strings.HasPrefix(err.Error(), "server.cfg -> conf/ports.cfg: could not parse array") {
+			t.Fatalf("expected error with the include chain, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "line 2") {
+			t.Fatalf("expected error on line 2, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var v target
+
+		_, err := LoadFilesFS(MapFS{"server.cfg": []byte("include: missing.cfg")}, &v, "server.cfg")
+
+		fileErr, ok := err.(*FileError)
+		if !ok || !reflect.DeepEqual(fileErr.Chain, []string{"server.cfg", "missing.cfg"}) {
+			t.Fatalf("expected *FileError for missing.cfg, got %v", err)
+		}
+	})
+
+	t.Run("invalid include", func(t *testing.T) {
+		var v target
+
+		_, err := LoadFilesFS(MapFS{"server.cfg": []byte("include: { a: b }")}, &v, "server.cfg")
+		if _, ok := errors.Cause(err).(*SyntaxError); !ok {
+			t.Fatalf("expected *SyntaxError, got %v", err)
+		}
+	})
+
+	t.Run("load", func(t *testing.T) {
+		var v target
+
+		err := Load(&v, LoadOptions{
+			Path: "server.cfg",
+			FS: MapFS{
+				"server.cfg": []byte("include: ports.cfg\nname: test"),
+				"ports.cfg":  []byte("port: 7788"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Port != 7788 || v.Name != "test" {
+			t.Fatalf("wrong value loaded, got %+v", v)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+// Package diff computes line based unified diffs
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines around each change
+const context = 3
+
+type opKind byte
+
+const (
+	equal   opKind = ' '
+	removed opKind = '-'
+	added   opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	text string
+}
+
+// Unified returns the unified diff between a and b, labelled with the
+// names oldName and newName, empty when they are equal
+func Unified(oldName string, newName string, a []byte, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+
+	ops := lineOps(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// the first change not yet written
+		for start < len(ops) && ops[start].kind == equal {
+			start++
+		}
+
+		if start == len(ops) {
+			break
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+
+		// extend the hunk while the next change is close enough to share context
+		end, unchanged := start, 0
+
+		for end < len(ops) && unchanged <= 2*context {
+			if ops[end].kind == equal {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+
+			end++
+		}
+
+		if unchanged > context {
+			end -= unchanged - context
+		}
+
+		writeHunk(&buf, ops, from, end)
+		start = end
+	}
+
+	return buf.Bytes()
+}
+
+func writeHunk(buf *bytes.Buffer, ops []op, from int, end int) {
+	oldStart, newStart := 0, 0
+
+	for _, o := range ops[0:from] {
+		if o.kind != added {
+			oldStart++
+		}
+
+		if o.kind != removed {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+
+	for _, o := range ops[from:end] {
+		if o.kind != added {
+			oldCount++
+		}
+
+		if o.kind != removed {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, o := range ops[from:end] {
+		buf.WriteByte(byte(o.kind))
+		buf.WriteString(o.text)
+
+		if !strings.HasSuffix(o.text, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the range of a hunk, lines before it and its line count
+func hunkRange(before int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits data after each new line
+func splitLines(data []byte) []string {
+	var lines []string
+
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			end = len(data)
+		}
+
+		lines = append(lines, string(data[0:end]))
+		data = data[end:]
+	}
+
+	return lines
+}
+
+// lineOps returns the operations turning a into b. The lines a and b start
+// and end with are equal, the others come from their longest common
+// subsequence
+func lineOps(a []string, b []string) []op {
+	prefix := 0
+
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b)-prefix-suffix)
+
+	for _, line := range a[0:prefix] {
+		ops = append(ops, op{kind: equal, text: line})
+	}
+
+	ops = append(ops, lcsOps(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{kind: equal, text: line})
+	}
+
+	return ops
+}
+
+// lcsOps returns the operations turning a into b, from the longest common
+// subsequence of their lines
+func lcsOps(a []string, b []string) []op {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{kind: equal, text: a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{kind: removed, text: a[i]})
+			i++
+		default:
+			ops = append(ops, op{kind: added, text: b[j]})
+			j++
+		}
+	}
+
+	return ops
+}
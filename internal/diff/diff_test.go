@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name:     "change",
+			a:        "a\nb\nc\n",
+			b:        "a\nx\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name:     "insert into empty",
+			a:        "",
+			b:        "a\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:     "no new line",
+			a:        "a\nb",
+			b:        "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "separate hunks",
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:        "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(Unified("old", "new", []byte(c.a), []byte(c.b)))
+
+			if got != c.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnifiedSharedContext(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n"
+	b := "x\n2\n3\n4\n5\n6\ny\n"
+
+	got := string(Unified("old", "new", []byte(a), []byte(b)))
+
+	if strings.Count(got, "@@ -") != 1 {
+		t.Fatalf("expected a single hunk, got\n%s", got)
+	}
+}
+
+func TestUnifiedLargeInput(t *testing.T) {
+	lines := make([]string, 100000)
+
+	for i := range lines {
+		lines[i] = "line\n"
+	}
+
+	a := strings.Join(lines, "")
+
+	lines[50000] = "changed\n"
+
+	b := strings.Join(lines, "")
+
+	got := string(Unified("old", "new", []byte(a), []byte(b)))
+	expected := "--- old\n+++ new\n@@ -49998,7 +49998,7 @@\n line\n line\n line\n-line\n+changed\n line\n line\n line\n"
+
+	if got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,82 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LookupFunc returns the value of the variable name and whether it is set,
+// os.LookupEnv is the default one
+type LookupFunc func(name string) (string, bool)
+
+// VariableError is returned when a value references a variable that is not set
+type VariableError struct {
+	Name string
+	Pos  Position
+}
+
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("could not decode line %d, variable %q is not set", e.Pos.Line, e.Name)
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} in value, $${ is kept as
+// a literal ${. pos is the position of the first character of value
+func interpolate(value string, pos Position, lookup LookupFunc) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if strings.HasPrefix(value[i:], "$${") {
+			b.WriteString("${")
+			i += 2
+			continue
+		}
+
+		if !strings.HasPrefix(value[i:], "${") {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		varPos := Position{Line: pos.Line, Column: pos.Column + i}
+
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			return "", &SyntaxError{Msg: "unterminated variable", Pos: varPos}
+		}
+
+		name := value[i+2 : i+end]
+		defaultValue := ""
+		hasDefault := false
+
+		if sep := strings.Index(name, ":-"); sep >= 0 {
+			defaultValue = name[sep+2:]
+			name = name[0:sep]
+			hasDefault = true
+		}
+
+		if len(name) == 0 {
+			return "", &SyntaxError{Msg: "empty variable name", Pos: varPos}
+		}
+
+		v, ok := lookup(name)
+
+		if hasDefault && len(v) == 0 {
+			v = defaultValue
+		} else if !ok {
+			return "", &VariableError{Name: name, Pos: varPos}
+		}
+
+		b.WriteString(v)
+		i += end
+	}
+
+	return b.String(), nil
+}
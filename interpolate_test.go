@@ -0,0 +1,138 @@
+package cfg
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		switch name {
+		case "TOKEN":
+			return "secret", true
+		case "EMPTY":
+			return "", true
+		}
+
+		return "", false
+	}
+
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{"no variables", "no variables"},
+		{"${TOKEN}", "secret"},
+		{"token-${TOKEN}-end", "token-secret-end"},
+		{"${MISSING:-default}", "default"},
+		{"${EMPTY:-default}", "default"},
+		{"${TOKEN:-default}", "secret"},
+		{"${EMPTY}", ""},
+		{"$${TOKEN}", "${TOKEN}"},
+		{"$TOKEN $ {TOKEN}", "$TOKEN $ {TOKEN}"},
+	}
+
+	for _, c := range cases {
+		got, err := interpolate(c.value, Position{Line: 1, Column: 1}, lookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.expected {
+			t.Fatalf("wrong value for %q, expected %q, got %q", c.value, c.expected, got)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := interpolate("abc ${MISSING}", Position{Line: 3, Column: 5}, lookup)
+
+		varErr, ok := err.(*VariableError)
+		if !ok {
+			t.Fatalf("expected *VariableError, got %v", err)
+		}
+
+		if varErr.Name != "MISSING" || varErr.Pos != (Position{Line: 3, Column: 9}) {
+			t.Fatalf("wrong error, got %q at %s", varErr.Name, varErr.Pos)
+		}
+
+		for _, value := range []string{"${TOKEN", "${}", "${:-x}"} {
+			_, err := interpolate(value, Position{Line: 1, Column: 1}, lookup)
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Fatalf("expected *SyntaxError for %q, got %v", value, err)
+			}
+		}
+	})
+}
+
+func TestDecoderInterpolate(t *testing.T) {
+	v := struct {
+		Token string   `cfg:"token"`
+		Port  int      `cfg:"port"`
+		Tags  []string `cfg:"tags"`
+		Voice struct {
+			Host string `cfg:"host"`
+		} `cfg:"voice"`
+	}{}
+
+	err := os.Setenv("CFG_TEST_TOKEN", "from-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Unsetenv("CFG_TEST_TOKEN")
+
+	input := `token: '${CFG_TEST_TOKEN}'
+port: ${CFG_TEST_PORT:-7788}
+tags: [${CFG_TEST_TOKEN}, '$${literal}']
+voice: {
+  host: ${CFG_TEST_HOST:-localhost}
+}`
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.Interpolate(nil)
+
+	err = dec.Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Token != "from-env" || v.Port != 7788 || v.Voice.Host != "localhost" {
+		t.Fatalf("wrong value decoded, got %+v", v)
+	}
+
+	if len(v.Tags) != 2 || v.Tags[0] != "from-env" || v.Tags[1] != "${literal}" {
+		t.Fatalf("wrong value decoded, got %v", v.Tags)
+	}
+
+	t.Run("not enabled by default", func(t *testing.T) {
+		err := Unmarshal([]byte("token: ${CFG_TEST_TOKEN}"), &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Token != "${CFG_TEST_TOKEN}" {
+			t.Fatalf("wrong value decoded, got %q", v.Token)
+		}
+	})
+
+	t.Run("positioned error", func(t *testing.T) {
+		dec := NewDecoder(strings.NewReader("port: 1\nvoice: {\n  host: '${CFG_TEST_MISSING}'\n}"))
+		dec.Interpolate(func(string) (string, bool) {
+			return "", false
+		})
+
+		err := dec.Decode(&v)
+
+		varErr, ok := errors.Cause(err).(*VariableError)
+		if !ok {
+			t.Fatalf("expected *VariableError, got %v", err)
+		}
+
+		if varErr.Pos != (Position{Line: 3, Column: 10}) {
+			t.Fatalf("wrong position, expected 3:10, got %s", varErr.Pos)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonNumber matches the unquoted values written as JSON numbers
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// ToJSON converts data to JSON, keeping the order of the keys. Inner
+// structs become objects, unquoted numbers and true and false become
+// numbers and booleans and anything else becomes a string. When a key is
+// repeated, at the position of the first one, inner structs are merged and
+// arrays appended like Unmarshal does, otherwise the last value is used
+func ToJSON(data []byte) ([]byte, error) {
+	root, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(jsonValue(root), "", "  ")
+}
+
+func jsonValue(n *Node) interface{} {
+	switch n.Kind {
+	case ObjectNode:
+		o := newObject()
+
+		for _, e := range n.Entries {
+			value := jsonValue(e.Value)
+
+			if current, ok := o.values[e.Key]; ok {
+				value = mergeValues(current, value)
+			}
+
+			o.set(e.Key, value)
+		}
+
+		return o
+	case ArrayNode:
+		items := make([]interface{}, 0, len(n.Items))
+
+		for _, item := range n.Items {
+			items = append(items, jsonValue(item))
+		}
+
+		return items
+	}
+
+	if n.Quote == 0 {
+		switch {
+		case jsonNumber.MatchString(n.Value):
+			return json.Number(n.Value)
+		case n.Value == "true":
+			return true
+		case n.Value == "false":
+			return false
+		}
+	}
+
+	return n.Value
+}
+
+// FromJSON converts the JSON object data to CFG, keeping the order of the
+// keys, in the format of Format. Numbers and booleans are written
+// unquoted and strings quoted. Null and strings with a new line or both
+// quotation marks can't be written and return an error
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := jsonNode(dec, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert JSON")
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("could not convert JSON, unexpected data after the top level object")
+	}
+
+	if root.Kind != ObjectNode {
+		return nil, errors.New(fmt.Sprintf("could not convert JSON, expected an object, got %s", root.Kind))
+	}
+
+	return FormatNode(root), nil
+}
+
+// jsonNode returns the node of the next JSON value of dec, at path
+func jsonNode(dec *json.Decoder, path string) (*Node, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '[' {
+			n := &Node{Kind: ArrayNode}
+
+			for i := 0; dec.More(); i++ {
+				item, err := jsonNode(dec, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+
+				n.Items = append(n.Items, item)
+			}
+
+			_, err = dec.Token()
+			return n, err
+		}
+
+		n := &Node{Kind: ObjectNode}
+
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			key := token.(string)
+			keyPath := joinPath(path, key)
+
+			if err := checkJSONString(keyPath, key); err != nil {
+				return nil, err
+			}
+
+			value, err := jsonNode(dec, keyPath)
+			if err != nil {
+				return nil, err
+			}
+
+			n.Entries = append(n.Entries, &Entry{Key: key, Value: value})
+		}
+
+		_, err = dec.Token()
+		return n, err
+	case string:
+		if err := checkJSONString(path, v); err != nil {
+			return nil, err
+		}
+
+		return &Node{Kind: ScalarNode, Value: v, Quote: '\''}, nil
+	case json.Number:
+		return &Node{Kind: ScalarNode, Value: v.String()}, nil
+	case bool:
+		return &Node{Kind: ScalarNode, Value: fmt.Sprint(v)}, nil
+	}
+
+	if len(path) == 0 {
+		return nil, errors.New("expected an object, got null")
+	}
+
+	return nil, errors.New(fmt.Sprintf("%s is null, which can't be written", path))
+}
+
+// checkJSONString returns an error when the key or value s at path can't
+// be written quoted
+func checkJSONString(path string, s string) error {
+	if strings.Contains(s, "\n") {
+		return errors.New(fmt.Sprintf("%s has a new line, which can't be written", path))
+	}
+
+	if strings.Contains(s, "'") && strings.Contains(s, `"`) {
+		return errors.New(fmt.Sprintf("%s has both ' and \", which can't be written", path))
+	}
+
+	return nil
+}
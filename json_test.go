@@ -0,0 +1,162 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	data := `# the server
+name: 'TestServer'
+port: 7788
+ratio: -1.5e3
+code: 007
+debug: true
+announce: 'false'
+answer: yes
+empty: ,
+modules: [js, 'csharp']
+voice: {
+  externalPort: 7798
+  tags: []
+}
+name: 'Last'
+`
+
+	expected := `{
+  "name": "Last",
+  "port": 7788,
+  "ratio": -1.5e3,
+  "code": "007",
+  "debug": true,
+  "announce": "false",
+  "answer": "yes",
+  "empty": "",
+  "modules": [
+    "js",
+    "csharp"
+  ],
+  "voice": {
+    "externalPort": 7798,
+    "tags": []
+  }
+}`
+
+	out, err := ToJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	_, err = ToJSON([]byte("a: ["))
+	if err == nil {
+		t.Fatalf("expected a syntax error")
+	}
+}
+
+func TestToJSONRepeated(t *testing.T) {
+	data := `voice: { a: 1, tags: [x], inner: { b: 1 } }
+port: 1
+voice: { b: 2, tags: [y], inner: { c: 2 }, a: 3 }
+port: { a: 1 }
+`
+
+	out, err := ToJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"voice":{"a":3,"tags":["x","y"],"inner":{"b":1,"c":2},"b":2},"port":{"a":1}}`
+
+	var buf bytes.Buffer
+
+	err = json.Compact(&buf, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	data := `{
+  "name": "TestServer",
+  "port": 7788,
+  "ratio": -1.5e3,
+  "debug": false,
+  "description": "it's a server",
+  "a key": "",
+  "modules": ["js", 2],
+  "voice": {"externalPort": 7798, "tags": [], "inner": {}},
+  "zeta": 1
+}`
+
+	expected := `name: 'TestServer'
+port: 7788
+ratio: -1.5e3
+debug: false
+description: "it's a server"
+a key: ''
+modules: [
+  'js',
+  2
+]
+voice: {
+  externalPort: 7798
+  tags: []
+  inner: {}
+}
+zeta: 1
+`
+
+	out, err := FromJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	back, err := ToJSON(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := FromJSON(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(again) != expected {
+		t.Fatalf("expected the round trip to keep the values, got\n%s", again)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{data: `[1, 2]`, err: "could not convert JSON, expected an object, got array"},
+		{data: `null`, err: "could not convert JSON: expected an object, got null"},
+		{data: `{"a": {"b": null}}`, err: "could not convert JSON: a.b is null, which can't be written"},
+		{data: `{"a": ["x\ny"]}`, err: "could not convert JSON: a[0] has a new line, which can't be written"},
+		{data: `{"a'\"": 1}`, err: "could not convert JSON: a'\" has both ' and \", which can't be written"},
+		{data: `{"a": 1} {}`, err: "could not convert JSON, unexpected data after the top level object"},
+		{data: `{"a": 1`, err: "could not convert JSON: unexpected end of JSON input"},
+	}
+
+	for _, test := range tests {
+		_, err := FromJSON([]byte(test.data))
+		if err == nil || err.Error() != test.err {
+			t.Fatalf("expected error %q for %s, got %v", test.err, test.data, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// Package lint checks CFG files for problems that are valid syntax but
+// likely mistakes or inconsistent style, with rules that can be extended
+package lint
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/crossworth/cfg"
+	"github.com/pkg/errors"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	// Error is a finding that likely breaks the config
+	Error Severity = "error"
+	// Warning is a finding that is likely a mistake
+	Warning Severity = "warning"
+	// Info is a style finding
+	Info Severity = "info"
+)
+
+// syntaxRule is the rule of the findings of files that can't be parsed
+const syntaxRule = "syntax"
+
+// Finding is a problem found by a rule
+type Finding struct {
+	File     string
+	Pos      cfg.Position
+	Rule     string
+	Severity Severity
+	Msg      string
+	// Fix fixes the finding, nil when it has no mechanical fix
+	Fix *Fix
+}
+
+// Fix is a mechanical change fixing a finding, applied by Linter.Fix
+type Fix struct {
+	// Before and After are snippets of the document before and after the
+	// fix, After is empty when the fix removes the snippet
+	Before string
+	After  string
+	// Apply changes the document the finding was found on
+	Apply func()
+}
+
+// File is a parsed CFG file checked by the rules
+type File struct {
+	Name string
+	Data []byte
+	// Lines are the lines of Data, without the new line
+	Lines []string
+	// Root is the document returned by cfg.ParseDocument
+	Root *cfg.Node
+}
+
+// Rule checks a file, rules must be safe to use from multiple goroutines
+type Rule interface {
+	// Name identifies the rule on findings, flags and ignore comments
+	Name() string
+	// Description is a sentence describing what the rule checks
+	Description() string
+	Check(f *File) []Finding
+}
+
+// rule is a Rule implemented by a function
+type rule struct {
+	name        string
+	description string
+	check       func(f *File) []Finding
+}
+
+func (r *rule) Name() string {
+	return r.name
+}
+
+func (r *rule) Description() string {
+	return r.description
+}
+
+func (r *rule) Check(f *File) []Finding {
+	return r.check(f)
+}
+
+// Linter checks files with a set of rules
+type Linter struct {
+	rules    []Rule
+	disabled map[string]bool
+}
+
+// New returns a Linter checking files with rules, see Rules for the built-in ones
+func New(rules ...Rule) *Linter {
+	return &Linter{
+		rules:    rules,
+		disabled: make(map[string]bool),
+	}
+}
+
+// Rules returns the rules of the linter, including disabled ones
+func (l *Linter) Rules() []Rule {
+	return l.rules
+}
+
+// Enabled reports whether the rule with name runs
+func (l *Linter) Enabled(name string) bool {
+	return !l.disabled[name]
+}
+
+// Disable stops the rules with the given names from running
+func (l *Linter) Disable(names ...string) error {
+	return l.toggle(names, true)
+}
+
+// Enable runs the rules with the given names again
+func (l *Linter) Enable(names ...string) error {
+	return l.toggle(names, false)
+}
+
+func (l *Linter) toggle(names []string, disabled bool) error {
+	for _, name := range names {
+		if l.rule(name) == nil {
+			return errors.New("unknown rule " + name)
+		}
+
+		l.disabled[name] = disabled
+	}
+
+	return nil
+}
+
+func (l *Linter) rule(name string) Rule {
+	for _, r := range l.rules {
+		if r.Name() == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// Lint checks the file name with content data and returns the findings
+// ordered by position. A file that can't be parsed has a single finding,
+// of the syntax rule. Findings can be ignored with a comment on the same
+// line or the line above, like # cfglint:ignore or # cfglint:ignore
+// duplicate-keys,empty-array to ignore only some rules
+func (l *Linter) Lint(name string, data []byte) []Finding {
+	_, findings, err := l.check(name, data)
+	if err != nil {
+		finding := Finding{File: name, Rule: syntaxRule, Severity: Error, Msg: err.Error()}
+
+		if syntax, ok := err.(*cfg.SyntaxError); ok {
+			finding.Pos = syntax.Pos
+			finding.Msg = syntax.Msg
+		}
+
+		return []Finding{finding}
+	}
+
+	return findings
+}
+
+// Fix applies the fixes of the findings of the file name with content
+// data, see Lint, and returns the fixed file with the findings fixed. The
+// file is rewritten in the format of cfg.Format, keeping comments, and
+// is returned unchanged when there is nothing to fix
+func (l *Linter) Fix(name string, data []byte) ([]byte, []Finding, error) {
+	f, findings, err := l.check(name, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fixed []Finding
+
+	for _, finding := range findings {
+		if finding.Fix != nil {
+			finding.Fix.Apply()
+			fixed = append(fixed, finding)
+		}
+	}
+
+	if len(fixed) == 0 {
+		return data, nil, nil
+	}
+
+	return cfg.FormatNode(f.Root), fixed, nil
+}
+
+// check parses data and returns the findings of the enabled rules that
+// are not ignored, ordered by position
+func (l *Linter) check(name string, data []byte) (*File, []Finding, error) {
+	root, err := cfg.ParseDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f := &File{
+		Name:  name,
+		Data:  data,
+		Lines: strings.Split(string(data), "\n"),
+		Root:  root,
+	}
+
+	ignored := ignoredRules(f.Lines, root)
+
+	var findings []Finding
+
+	for _, r := range l.rules {
+		if l.disabled[r.Name()] {
+			continue
+		}
+
+		for _, finding := range r.Check(f) {
+			finding.File = name
+			finding.Rule = r.Name()
+
+			if rules, ok := ignored[finding.Pos.Line]; ok && (rules == nil || rules[finding.Rule]) {
+				continue
+			}
+
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return f, findings, nil
+}
+
+var ignoreComment = regexp.MustCompile(`#\s*cfglint:ignore\b([ \t]+[\w,-]+)?`)
+
+// ignoredRules returns the rules ignored on each line, nil when every rule
+// is ignored. A comment alone on its line applies to the next line, the
+// comment after a value written on multiple lines also applies to its key
+func ignoredRules(lines []string, root *cfg.Node) map[int]map[string]bool {
+	ignored := make(map[int]map[string]bool)
+
+	for i, text := range lines {
+		rules, ok := parseIgnore(text)
+		if !ok {
+			continue
+		}
+
+		line := i + 1
+
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			line++
+		}
+
+		ignored[line] = rules
+	}
+
+	walk(root, "", func(path string, e *cfg.Entry) {
+		if rules, ok := parseIgnore(e.Comment); ok {
+			ignored[e.Pos.Line] = rules
+		}
+	})
+
+	return ignored
+}
+
+// parseIgnore returns the rules of the ignore comment in text, nil when
+// it ignores every rule, ok is false when text has no ignore comment
+func parseIgnore(text string) (rules map[string]bool, ok bool) {
+	match := ignoreComment.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+
+	if names := strings.TrimSpace(match[1]); len(names) > 0 {
+		rules = make(map[string]bool)
+
+		for _, name := range strings.Split(names, ",") {
+			rules[name] = true
+		}
+	}
+
+	return rules, true
+}
+
+// walk calls fn for every entry of n and its inner structs and arrays,
+// with the key path of the entry
+func walk(n *cfg.Node, prefix string, fn func(path string, e *cfg.Entry)) {
+	switch n.Kind {
+	case cfg.ObjectNode:
+		for _, e := range n.Entries {
+			path := joinPath(prefix, e.Key)
+
+			fn(path, e)
+			walk(e.Value, path, fn)
+		}
+	case cfg.ArrayNode:
+		for _, item := range n.Items {
+			walk(item, prefix, fn)
+		}
+	}
+}
+
+// walkNodes calls fn for n and every node inside it
+func walkNodes(n *cfg.Node, fn func(n *cfg.Node)) {
+	fn(n)
+
+	for _, item := range n.Items {
+		walkNodes(item, fn)
+	}
+
+	for _, e := range n.Entries {
+		walkNodes(e.Value, fn)
+	}
+}
+
+func joinPath(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+
+	return prefix + "." + key
+}
@@ -0,0 +1,366 @@
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crossworth/cfg"
+)
+
+// format returns the findings as line:column rule msg, one per line
+func format(findings []Finding) string {
+	var lines []string
+
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("%d:%d %s %s", f.Pos.Line, f.Pos.Column, f.Rule, f.Msg))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "duplicate keys",
+			rule:     "duplicate-keys",
+			data:     "port: 1\nvoice: {\n  port: 2\n  port: 3\n}\nport: 4\n",
+			expected: "4:3 duplicate-keys voice.port is repeated, first set on line 3\n6:1 duplicate-keys port is repeated, first set on line 1",
+		},
+		{
+			name:     "duplicate keys in array elements",
+			rule:     "duplicate-keys",
+			data:     "a: [{ b: 1, b: 2 }, { b: 3 }]",
+			expected: "1:13 duplicate-keys a.b is repeated, first set on line 1",
+		},
+		{
+			name:     "suspicious bools",
+			rule:     "suspicious-bool",
+			data:     "a: yes\nb: 'no'\nc: [off, true, True]\nd: on\n",
+			expected: "1:4 suspicious-bool yes looks like a boolean, write true\n3:5 suspicious-bool off looks like a boolean, write false\n3:16 suspicious-bool True looks like a boolean, write true\n4:4 suspicious-bool on is decoded as false, write true",
+		},
+		{
+			name:     "mixed quotes",
+			rule:     "mixed-quotes",
+			data:     "a: 'x'\nb: 'y'\nc: \"z\"\nd: \"it's\"\n",
+			expected: "3:4 mixed-quotes value uses double quotes, most values use single quotes",
+		},
+		{
+			name:     "mixed quotes tie",
+			rule:     "mixed-quotes",
+			data:     "a: \"x\"\nb: 'y'\n",
+			expected: "1:4 mixed-quotes value uses double quotes, most values use single quotes",
+		},
+		{
+			name:     "trailing whitespace",
+			rule:     "trailing-whitespace",
+			data:     "a: 1 \nb: 2\r\n# c\t\n",
+			expected: "1:5 trailing-whitespace trailing whitespace\n3:4 trailing-whitespace trailing whitespace",
+		},
+		{
+			name:     "missing comma",
+			rule:     "mixed-commas",
+			data:     "a: [\n  1,\n  2\n  3,\n  4\n]\n",
+			expected: "3:3 mixed-commas missing comma, most array elements here are followed by one",
+		},
+		{
+			name:     "unneeded comma",
+			rule:     "mixed-commas",
+			data:     "a: 1,\nb: 2\nc: 3\nd: [1, 2, 3]\n",
+			expected: "1:4 mixed-commas unneeded comma, most keys here are not followed by one",
+		},
+		{
+			name: "commas on a single line",
+			rule: "mixed-commas",
+			data: "a: 1, b: 2\nc: 3\n",
+		},
+		{
+			name:     "empty arrays",
+			rule:     "empty-array",
+			data:     "a: []\nb: { c: [] }\nd: [1]\n",
+			expected: "1:1 empty-array a is an empty array\n2:6 empty-array b.c is an empty array",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := New(Rules()...)
+
+			for _, r := range l.Rules() {
+				if r.Name() != test.rule {
+					_ = l.Disable(r.Name())
+				}
+			}
+
+			got := format(l.Lint("test.cfg", []byte(test.data)))
+			if got != test.expected {
+				t.Fatalf("expected findings\n%s\ngot\n%s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestLintSyntaxError(t *testing.T) {
+	findings := New(Rules()...).Lint("test.cfg", []byte("a: [1, 2"))
+
+	if len(findings) != 1 || findings[0].Rule != syntaxRule || findings[0].Severity != Error || findings[0].File != "test.cfg" {
+		t.Fatalf("expected a syntax finding, got %+v", findings)
+	}
+}
+
+func TestLintIgnore(t *testing.T) {
+	data := `a: yes # cfglint:ignore
+# cfglint:ignore suspicious-bool
+b: no
+c: off # cfglint:ignore empty-array,duplicate-keys
+# cfglint:ignore empty-array
+d: []
+e: [
+] # cfglint:ignore
+f: {
+  g: []
+} # cfglint:ignore empty-array
+`
+
+	got := format(New(Rules()...).Lint("test.cfg", []byte(data)))
+	expected := "4:4 suspicious-bool off looks like a boolean, write false\n10:3 empty-array f.g is an empty array"
+
+	if got != expected {
+		t.Fatalf("expected findings\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestLinterToggle(t *testing.T) {
+	l := New(Rules()...)
+
+	if err := l.Disable("empty-array"); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Enabled("empty-array") || !l.Enabled("duplicate-keys") {
+		t.Fatalf("expected only empty-array to be disabled")
+	}
+
+	if findings := l.Lint("test.cfg", []byte("a: []")); len(findings) != 0 {
+		t.Fatalf("expected no findings, got %+v", findings)
+	}
+
+	if err := l.Enable("empty-array"); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings := l.Lint("test.cfg", []byte("a: []")); len(findings) != 1 {
+		t.Fatalf("expected a finding, got %+v", findings)
+	}
+
+	if err := l.Disable("unknown"); err == nil || err.Error() != "unknown rule unknown" {
+		t.Fatalf("expected unknown rule error, got %v", err)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "port": {"type": "integer"},
+    "voice": {
+      "type": "object",
+      "properties": {"bitrate": {"type": "integer"}}
+    },
+    "servers": {
+      "type": "array",
+      "items": {"type": "object", "properties": {"host": {"type": "string"}}}
+    },
+    "extra": {"type": "object", "properties": {}, "additionalProperties": true}
+  }
+}`
+
+	r, err := UnknownKeys([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := `include: 'resources.cfg'
+prot: 1
+voice: { bitRates: 1, codec: opus }
+servers: [{ hots: a }]
+extra: { any: 1 }
+`
+
+	got := format(New(r).Lint("test.cfg", []byte(data)))
+	expected := `2:1 unknown-keys unknown key prot, did you mean port?
+3:10 unknown-keys unknown key voice.bitRates, did you mean bitrate?
+3:23 unknown-keys unknown key voice.codec
+4:13 unknown-keys unknown key servers.hots, did you mean host?`
+
+	if got != expected {
+		t.Fatalf("expected findings\n%s\ngot\n%s", expected, got)
+	}
+
+	if _, err := UnknownKeys([]byte("{")); err == nil {
+		t.Fatalf("expected an error for an invalid schema")
+	}
+}
+
+func TestLinterFix(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+		fixes    string
+	}{
+		{
+			name:     "bools without a schema are not fixed",
+			data:     "a: yes\nb: [Off, 'no', True]\n",
+			expected: "a: yes\nb: [Off, 'no', True]\n",
+		},
+		{
+			name:     "duplicate keys keep the last one",
+			data:     "# first\nport: 1\n# second\nport: 2\nport: 3 # last\nvoice: { port: 1, port: 2 }\n",
+			expected: "# first\n# second\nport: 3 # last\nvoice: {\n  port: 2\n}\n",
+			fixes:    "port: 1>|port: 2>|port: 1>",
+		},
+		{
+			name:     "repeated inner structs, arrays and includes are kept",
+			data:     "include: 'a.cfg'\ninclude: 'b.cfg'\nvoice: { a: 1 }\nvoice: { b: 2 }\nc: [1]\nc: [2]\nd: 1\nd: { e: 1 }\n",
+			expected: "include: 'a.cfg'\ninclude: 'b.cfg'\nvoice: { a: 1 }\nvoice: { b: 2 }\nc: [1]\nc: [2]\nd: 1\nd: { e: 1 }\n",
+		},
+		{
+			name:     "quotes",
+			data:     "a: 'x'\nb: 'y'\nc: \"z\"\nd: \"it's\"\n",
+			expected: "a: 'x'\nb: 'y'\nc: 'z'\nd: \"it's\"\n",
+			fixes:    "\"z\">'z'",
+		},
+		{
+			name:     "sorted resources",
+			data:     "resources: [\n  # core\n  race,\n  chat, # the chat\n  admin\n]\n",
+			expected: "resources: [\n  admin,\n  chat, # the chat\n  # core\n  race\n]\n",
+			fixes:    "resources: [\n  # core\n  race,\n  chat, # the chat\n  admin\n]>resources: [\n  admin,\n  chat, # the chat\n  # core\n  race\n]",
+		},
+		{
+			name:     "ignored findings",
+			data:     "a: \"x\"\nb: 'y'\nc: yes # cfglint:ignore\n",
+			expected: "a: 'x'\nb: 'y'\nc: yes # cfglint:ignore\n",
+			fixes:    "\"x\">'x'",
+		},
+		{
+			name:     "nothing to fix",
+			data:     "a:   []\n",
+			expected: "a:   []\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := New(append(Rules(), OptionalRules()...)...)
+
+			fixed, findings, err := l.Fix("test.cfg", []byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(fixed) != test.expected {
+				t.Fatalf("expected fixed file\n%s\ngot\n%s", test.expected, fixed)
+			}
+
+			var fixes []string
+
+			for _, f := range findings {
+				fixes = append(fixes, f.Fix.Before+">"+f.Fix.After)
+			}
+
+			if got := strings.Join(fixes, "|"); got != test.fixes {
+				t.Fatalf("expected fixes %q, got %q", test.fixes, got)
+			}
+		})
+	}
+
+	if _, _, err := New(Rules()...).Fix("test.cfg", []byte("a: [")); err == nil {
+		t.Fatalf("expected a syntax error")
+	}
+}
+
+// fixConfig is decoded before and after fixing a file, the values should not change
+type fixConfig struct {
+	Name     string `cfg:"name"`
+	Port     int    `cfg:"port"`
+	Debug    bool   `cfg:"debug"`
+	Announce bool   `cfg:"announce"`
+	Flags    []bool `cfg:"flags"`
+	Voice    struct {
+		Enabled bool   `cfg:"enabled"`
+		Codec   string `cfg:"codec"`
+		Port    int    `cfg:"port"`
+	} `cfg:"voice"`
+}
+
+func TestSuspiciousBoolsFix(t *testing.T) {
+	schema, err := cfg.JSONSchema(&fixConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bools, err := SuspiciousBools(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := Rules()
+
+	for i, r := range rules {
+		if r.Name() == bools.Name() {
+			rules[i] = bools
+		}
+	}
+
+	data := `name: yes
+port: 1
+port: 2
+debug: Yes
+announce: on
+flags: [y, off, F]
+voice: { enabled: t, codec: no }
+voice: { port: 3, port: 4 }
+`
+
+	fixed, findings, err := New(rules...).Fix("test.cfg", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fixes []string
+
+	for _, f := range findings {
+		fixes = append(fixes, f.Fix.Before+">"+f.Fix.After)
+	}
+
+	expected := "port: 1>|Yes>true|y>true|F>false|t>true|port: 3>"
+
+	if got := strings.Join(fixes, "|"); got != expected {
+		t.Fatalf("expected fixes %q, got %q", expected, got)
+	}
+
+	var before, after fixConfig
+
+	if err := cfg.Unmarshal([]byte(data), &before); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Unmarshal(fixed, &after); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected the fixed file to decode to %+v, got %+v\n%s", before, after, fixed)
+	}
+
+	if _, err := SuspiciousBools([]byte("{")); err == nil {
+		t.Fatalf("expected an error for an invalid schema")
+	}
+}
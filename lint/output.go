@@ -0,0 +1,273 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report is the result of linting a set of files
+type Report struct {
+	// Files are the names of the files checked, including the ones without findings
+	Files    []string
+	Findings []Finding
+	// Rules are the rules used, they describe the findings on SARIF
+	Rules []Rule
+}
+
+// WriteText writes a line per finding, like
+// server.cfg:3:1: error: port is repeated, first set on line 1 (duplicate-keys)
+func WriteText(w io.Writer, r *Report) error {
+	for _, f := range r.Findings {
+		_, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s (%s)\n", f.File, f.Pos.Line, f.Pos.Column, f.Severity, f.Msg, f.Rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteFixes writes the fixed findings as text, with the lines of the
+// snippets before and after each fix prefixed by - and +
+func WriteFixes(w io.Writer, fixed []Finding) error {
+	for _, f := range fixed {
+		_, err := fmt.Fprintf(w, "%s:%d:%d: fixed: %s (%s)\n", f.File, f.Pos.Line, f.Pos.Column, f.Msg, f.Rule)
+		if err != nil {
+			return err
+		}
+
+		err = writeSnippet(w, "-", f.Fix.Before)
+		if err != nil {
+			return err
+		}
+
+		err = writeSnippet(w, "+", f.Fix.After)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeSnippet(w io.Writer, prefix string, snippet string) error {
+	if len(snippet) == 0 {
+		return nil
+	}
+
+	for _, line := range strings.Split(snippet, "\n") {
+		_, err := fmt.Fprintf(w, "\t%s %s\n", prefix, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type jsonFinding struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// WriteJSON writes the findings as a JSON array
+func WriteJSON(w io.Writer, r *Report) error {
+	findings := make([]jsonFinding, 0, len(r.Findings))
+
+	for _, f := range r.Findings {
+		findings = append(findings, jsonFinding{
+			File:     f.File,
+			Line:     f.Pos.Line,
+			Column:   f.Pos.Column,
+			Rule:     f.Rule,
+			Severity: f.Severity,
+			Message:  f.Msg,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(findings)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log, read by code
+// scanning tools
+func WriteSARIF(w io.Writer, r *Report) error {
+	rules := []sarifRule{{ID: syntaxRule, ShortDescription: sarifMessage{Text: "Files that can't be parsed."}}}
+
+	for _, rule := range r.Rules {
+		rules = append(rules, sarifRule{ID: rule.Name(), ShortDescription: sarifMessage{Text: rule.Description()}})
+	}
+
+	results := make([]sarifResult, 0, len(r.Findings))
+
+	for _, f := range r.Findings {
+		level := "note"
+
+		switch f.Severity {
+		case Error:
+			level = "error"
+		case Warning:
+			level = "warning"
+		}
+
+		results = append(results, sarifResult{
+			RuleID:  f.Rule,
+			Level:   level,
+			Message: sarifMessage{Text: f.Msg},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.File},
+					Region:           sarifRegion{StartLine: f.Pos.Line, StartColumn: f.Pos.Column},
+				},
+			}},
+		})
+	}
+
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "cfglint", Rules: rules}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the findings as a JUnit XML report, with a test case
+// per file failing with its findings
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitTestSuite{Name: "cfglint", Tests: len(r.Files)}
+	cases := make(map[string]int, len(r.Files))
+
+	for _, file := range r.Files {
+		cases[file] = len(suite.Cases)
+		suite.Cases = append(suite.Cases, junitTestCase{Name: file, ClassName: "cfglint"})
+	}
+
+	for _, f := range r.Findings {
+		i, ok := cases[f.File]
+
+		if !ok {
+			i = len(suite.Cases)
+			cases[f.File] = i
+			suite.Cases = append(suite.Cases, junitTestCase{Name: f.File, ClassName: "cfglint"})
+			suite.Tests++
+		}
+
+		if len(suite.Cases[i].Failures) == 0 {
+			suite.Failures++
+		}
+
+		suite.Cases[i].Failures = append(suite.Cases[i].Failures, junitFailure{
+			Message: f.Msg,
+			Type:    f.Rule,
+			Text:    fmt.Sprintf("%s:%d:%d: %s: %s", f.File, f.Pos.Line, f.Pos.Column, f.Severity, f.Msg),
+		})
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
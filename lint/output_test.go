@@ -0,0 +1,145 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func testReport() *Report {
+	l := New(Rules()...)
+
+	report := &Report{
+		Files: []string{"server.cfg", "resource.cfg"},
+		Rules: l.Rules(),
+	}
+
+	report.Findings = l.Lint("server.cfg", []byte("debug: yes\nport: 1\nport: 2\n"))
+	return report
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteText(&buf, testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `server.cfg:1:8: warning: yes looks like a boolean, write true (suspicious-bool)
+server.cfg:3:1: error: port is repeated, first set on line 2 (duplicate-keys)
+`
+
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteJSON(&buf, testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var findings []map[string]interface{}
+
+	err = json.Unmarshal(buf.Bytes(), &findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(findings) != 2 || findings[1]["rule"] != "duplicate-keys" || findings[1]["line"] != 3.0 || findings[1]["severity"] != "error" {
+		t.Fatalf("unexpected findings %s", buf.String())
+	}
+
+	buf.Reset()
+
+	err = WriteJSON(&buf, &Report{})
+	if err != nil || buf.String() != "[]\n" {
+		t.Fatalf("expected an empty array, got %q %v", buf.String(), err)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteSARIF(&buf, testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+
+	err = json.Unmarshal(buf.Bytes(), &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := log.Runs[0]
+
+	if log.Version != "2.1.0" || run.Tool.Driver.Name != "cfglint" || len(run.Tool.Driver.Rules) != len(Rules())+1 {
+		t.Fatalf("unexpected log %s", buf.String())
+	}
+
+	if len(run.Results) != 2 || run.Results[0].Level != "warning" || run.Results[1].Level != "error" {
+		t.Fatalf("unexpected results %s", buf.String())
+	}
+
+	location := run.Results[1].Locations[0].PhysicalLocation
+
+	if location.ArtifactLocation.URI != "server.cfg" || location.Region.StartLine != 3 || location.Region.StartColumn != 1 {
+		t.Fatalf("unexpected location %+v", location)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteJUnit(&buf, testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="cfglint" tests="2" failures="1">
+    <testcase name="server.cfg" classname="cfglint">
+      <failure message="yes looks like a boolean, write true" type="suspicious-bool">server.cfg:1:8: warning: yes looks like a boolean, write true</failure>
+      <failure message="port is repeated, first set on line 2" type="duplicate-keys">server.cfg:3:1: error: port is repeated, first set on line 2</failure>
+    </testcase>
+    <testcase name="resource.cfg" classname="cfglint"></testcase>
+  </testsuite>
+</testsuites>
+`
+
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteFixes(t *testing.T) {
+	_, fixed, err := New(Rules()...).Fix("server.cfg", []byte("a: 'x'\nb: 'y'\nc: \"z\"\nport: 1\nport: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	err = WriteFixes(&buf, fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `server.cfg:3:4: fixed: value uses double quotes, most values use single quotes (mixed-quotes)
+	- "z"
+	+ 'z'
+server.cfg:5:1: fixed: port is repeated, first set on line 4 (duplicate-keys)
+	- port: 1
+`
+
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,491 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crossworth/cfg"
+)
+
+// Rules returns new instances of the built-in rules, see their descriptions
+func Rules() []Rule {
+	return []Rule{
+		duplicateKeys(),
+		suspiciousBools(nil),
+		mixedQuotes(),
+		trailingWhitespace(),
+		mixedCommas(),
+		emptyArrays(),
+	}
+}
+
+// OptionalRules returns new instances of the built-in rules that should
+// only run when asked for, because their findings are not always mistakes
+func OptionalRules() []Rule {
+	return []Rule{
+		sortedResources(),
+	}
+}
+
+func duplicateKeys() Rule {
+	return &rule{
+		name:        "duplicate-keys",
+		description: "Keys set more than once in the same inner struct. A repeated value overrides the earlier one, while repeated inner structs are merged and repeated arrays are appended or replaced depending on the field and how the file is decoded. Every top level include is loaded.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			var check func(n *cfg.Node, prefix string)
+
+			check = func(n *cfg.Node, prefix string) {
+				for _, item := range n.Items {
+					check(item, prefix)
+				}
+
+				first := make(map[string]*cfg.Entry, len(n.Entries))
+				last := make(map[string]*cfg.Entry, len(n.Entries))
+
+				for _, e := range n.Entries {
+					path := joinPath(prefix, e.Key)
+
+					if previous, ok := last[e.Key]; ok {
+						finding := Finding{
+							Pos:      e.Pos,
+							Severity: Error,
+							Msg:      fmt.Sprintf("%s is repeated, first set on line %d", path, first[e.Key].Pos.Line),
+						}
+
+						// only a repeated value overrides the previous one,
+						// inner structs, arrays and includes are all used
+						if previous.Value.Kind == cfg.ScalarNode && e.Value.Kind == cfg.ScalarNode && !(len(prefix) == 0 && e.Key == includeKey) {
+							finding.Fix = removeDuplicate(n, previous)
+						}
+
+						findings = append(findings, finding)
+					} else {
+						first[e.Key] = e
+					}
+
+					last[e.Key] = e
+					check(e.Value, path)
+				}
+			}
+
+			check(f.Root, "")
+			return findings
+		},
+	}
+}
+
+// removeDuplicate returns the fix removing the entry e of n, repeated
+// later, the comments above it move to the entry after it
+func removeDuplicate(n *cfg.Node, e *cfg.Entry) *Fix {
+	return &Fix{
+		Before: snippet(e.Key, e.Value),
+		Apply: func() {
+			for i, other := range n.Entries {
+				if other != e {
+					continue
+				}
+
+				if i+1 < len(n.Entries) {
+					next := n.Entries[i+1]
+					next.Comments = append(e.Comments[:len(e.Comments):len(e.Comments)], next.Comments...)
+				}
+
+				n.Entries = append(n.Entries[:i], n.Entries[i+1:]...)
+				return
+			}
+		},
+	}
+}
+
+// boolValues are the unquoted values decoded as booleans, other than true and false
+var boolValues = map[string]bool{
+	"yes": true,
+	"y":   true,
+	"t":   true,
+	"no":  false,
+	"n":   false,
+	"f":   false,
+	"on":  true,
+	"off": false,
+}
+
+// fixedBool returns the value a suspicious boolean should be written as,
+// empty when the value is not suspicious
+func fixedBool(value string) string {
+	lower := strings.ToLower(value)
+
+	if lower == "true" || lower == "false" {
+		if lower != value {
+			return lower
+		}
+
+		return ""
+	}
+
+	if b, ok := boolValues[lower]; ok {
+		return fmt.Sprint(b)
+	}
+
+	return ""
+}
+
+// suspiciousBools returns the suspicious-bool rule, values are only fixed
+// when s describes them as booleans
+func suspiciousBools(s *schema) Rule {
+	return &rule{
+		name:        "suspicious-bool",
+		description: "Unquoted values like yes, no, on and off that look like booleans, only true and false are unambiguous.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			checkBools(f.Root, s, &findings)
+			return findings
+		},
+	}
+}
+
+func checkBools(n *cfg.Node, s *schema, findings *[]Finding) {
+	switch n.Kind {
+	case cfg.ObjectNode:
+		for _, e := range n.Entries {
+			checkBools(e.Value, s.property(e.Key), findings)
+		}
+
+		return
+	case cfg.ArrayNode:
+		for _, item := range n.Items {
+			checkBools(item, s.items(), findings)
+		}
+
+		return
+	}
+
+	if n.Quote != 0 {
+		return
+	}
+
+	fixed := fixedBool(n.Value)
+	if len(fixed) == 0 {
+		return
+	}
+
+	lower := strings.ToLower(n.Value)
+	msg := fmt.Sprintf("%s looks like a boolean, write %s", n.Value, fixed)
+
+	// on is not decoded as true, only true, yes, y, t and 1 are
+	if lower == "on" {
+		msg = fmt.Sprintf("%s is decoded as false, write %s", n.Value, fixed)
+	}
+
+	finding := Finding{
+		Pos:      n.Pos,
+		Severity: Warning,
+		Msg:      msg,
+	}
+
+	// strings keep the value as written, and on and off are fixed by hand
+	// because on is likely meant as true
+	if s.is("boolean") && lower != "on" && lower != "off" {
+		finding.Fix = &Fix{
+			Before: n.Value,
+			After:  fixed,
+			Apply: func() {
+				n.Value = fixed
+			},
+		}
+	}
+
+	*findings = append(*findings, finding)
+}
+
+// quoteStyle returns the quotation mark used by most quoted values of the
+// file, single quotes on a tie
+func quoteStyle(root *cfg.Node) byte {
+	counts := make(map[byte]int)
+
+	walkNodes(root, func(n *cfg.Node) {
+		if n.Kind == cfg.ScalarNode && n.Quote != 0 {
+			counts[n.Quote]++
+		}
+	})
+
+	if counts['"'] > counts['\''] {
+		return '"'
+	}
+
+	return '\''
+}
+
+func mixedQuotes() Rule {
+	return &rule{
+		name:        "mixed-quotes",
+		description: "Values quoted with a different quotation mark than most values of the file.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			style := quoteStyle(f.Root)
+
+			walkNodes(f.Root, func(n *cfg.Node) {
+				if n.Kind != cfg.ScalarNode || n.Quote == 0 || n.Quote == style || strings.IndexByte(n.Value, style) >= 0 {
+					return
+				}
+
+				finding := Finding{
+					Pos:      n.Pos,
+					Severity: Info,
+					Msg:      fmt.Sprintf("value uses %s, most values use %s", quoteName(n.Quote), quoteName(style)),
+				}
+
+				// fixed files are written by cfg.FormatNode, which only
+				// uses double quotes for values with a single quote
+				if canonical := canonicalQuote(n.Value); canonical != n.Quote {
+					finding.Fix = &Fix{
+						Before: quote(n.Quote, n.Value),
+						After:  quote(canonical, n.Value),
+						Apply: func() {
+							n.Quote = canonical
+						},
+					}
+				}
+
+				findings = append(findings, finding)
+			})
+
+			return findings
+		},
+	}
+}
+
+// canonicalQuote returns the quotation mark cfg.Format uses for value
+func canonicalQuote(value string) byte {
+	if strings.IndexByte(value, '\'') >= 0 {
+		return '"'
+	}
+
+	return '\''
+}
+
+func quote(mark byte, value string) string {
+	return string(mark) + value + string(mark)
+}
+
+func quoteName(quote byte) string {
+	if quote == '"' {
+		return "double quotes"
+	}
+
+	return "single quotes"
+}
+
+func trailingWhitespace() Rule {
+	return &rule{
+		name:        "trailing-whitespace",
+		description: "Spaces and tabs at the end of lines.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			for i, text := range f.Lines {
+				text = strings.TrimSuffix(text, "\r")
+				trimmed := strings.TrimRight(text, " \t")
+
+				if len(trimmed) == len(text) {
+					continue
+				}
+
+				findings = append(findings, Finding{
+					Pos:      cfg.Position{Line: i + 1, Column: len(trimmed) + 1},
+					Severity: Info,
+					Msg:      "trailing whitespace",
+				})
+			}
+
+			return findings
+		},
+	}
+}
+
+// separated is a value of an inner struct or array and whether it is
+// followed by a comma
+type separated struct {
+	pos   cfg.Position
+	comma bool
+}
+
+// commaAfter reports whether the single line scalar n is followed by a
+// comma, ok is false when n is not a scalar
+func commaAfter(lines []string, n *cfg.Node) (comma bool, ok bool) {
+	if n.Kind != cfg.ScalarNode || n.Pos.Line > len(lines) {
+		return false, false
+	}
+
+	text := lines[n.Pos.Line-1]
+	end := n.Pos.Column - 1 + len(n.Value)
+
+	if n.Quote != 0 {
+		end += 2
+	}
+
+	if end > len(text) {
+		return false, false
+	}
+
+	rest := strings.TrimLeft(text[end:], " \t\r")
+	return strings.HasPrefix(rest, ","), true
+}
+
+func mixedCommas() Rule {
+	return &rule{
+		name:        "mixed-commas",
+		description: "Keys or array elements followed by a comma next to others that are not, in the same inner struct or array.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			walkNodes(f.Root, func(n *cfg.Node) {
+				var values []*cfg.Node
+				var what string
+
+				switch n.Kind {
+				case cfg.ObjectNode:
+					for _, e := range n.Entries {
+						values = append(values, e.Value)
+					}
+
+					what = "keys"
+				case cfg.ArrayNode:
+					values = n.Items
+					what = "array elements"
+				default:
+					return
+				}
+
+				var elements []separated
+
+				commas := 0
+
+				// the last element does not need a separator, elements
+				// on the same line as the next one need one
+				for i := 0; i < len(values)-1; i++ {
+					comma, ok := commaAfter(f.Lines, values[i])
+					if !ok || values[i+1].Pos.Line == values[i].Pos.Line {
+						continue
+					}
+
+					elements = append(elements, separated{pos: values[i].Pos, comma: comma})
+
+					if comma {
+						commas++
+					}
+				}
+
+				// on a tie the canonical format wins, commas only between array elements
+				majority := commas*2 > len(elements) || commas*2 == len(elements) && n.Kind == cfg.ArrayNode
+
+				for _, element := range elements {
+					if element.comma == majority {
+						continue
+					}
+
+					msg := fmt.Sprintf("missing comma, most %s here are followed by one", what)
+
+					if element.comma {
+						msg = fmt.Sprintf("unneeded comma, most %s here are not followed by one", what)
+					}
+
+					findings = append(findings, Finding{Pos: element.pos, Severity: Info, Msg: msg})
+				}
+			})
+
+			return findings
+		},
+	}
+}
+
+func emptyArrays() Rule {
+	return &rule{
+		name:        "empty-array",
+		description: "Keys set to an empty array, which replaces any default or previously merged value.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			walk(f.Root, "", func(path string, e *cfg.Entry) {
+				if e.Value.Kind == cfg.ArrayNode && len(e.Value.Items) == 0 {
+					findings = append(findings, Finding{
+						Pos:      e.Pos,
+						Severity: Info,
+						Msg:      fmt.Sprintf("%s is an empty array", path),
+					})
+				}
+			})
+
+			return findings
+		},
+	}
+}
+
+// resourcesKey is the top level key listing the resources to load
+const resourcesKey = "resources"
+
+func sortedResources() Rule {
+	return &rule{
+		name:        "sorted-resources",
+		description: "A resources array not sorted by name, resources load in order so only use it when the order does not matter.",
+		check: func(f *File) []Finding {
+			for _, e := range f.Root.Entries {
+				if e.Key != resourcesKey || e.Value.Kind != cfg.ArrayNode {
+					continue
+				}
+
+				items := e.Value.Items
+
+				for _, item := range items {
+					if item.Kind != cfg.ScalarNode {
+						return nil
+					}
+				}
+
+				sorted := append([]*cfg.Node(nil), items...)
+
+				sort.SliceStable(sorted, func(i, j int) bool {
+					return sorted[i].Value < sorted[j].Value
+				})
+
+				for i, item := range items {
+					if item == sorted[i] {
+						continue
+					}
+
+					after := *e.Value
+					after.Items = sorted
+
+					return []Finding{{
+						Pos:      e.Pos,
+						Severity: Info,
+						Msg:      fmt.Sprintf("resources are not sorted, %s should come before %s", sorted[i].Value, item.Value),
+						Fix: &Fix{
+							Before: snippet(e.Key, e.Value),
+							After:  snippet(e.Key, &after),
+							Apply: func() {
+								e.Value.Items = sorted
+							},
+						},
+					}}
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// snippet returns key and value as written by cfg.FormatNode
+func snippet(key string, value *cfg.Node) string {
+	root := &cfg.Node{
+		Kind:    cfg.ObjectNode,
+		Entries: []*cfg.Entry{{Key: key, Value: value}},
+	}
+
+	return strings.TrimSuffix(string(cfg.FormatNode(root)), "\n")
+}
@@ -0,0 +1,203 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/crossworth/cfg"
+	"github.com/pkg/errors"
+)
+
+// includeKey is read by the loader, it is known to every schema
+const includeKey = "include"
+
+// schema is the part of a JSON Schema used to find unknown keys and the
+// type of values
+type schema struct {
+	// Type is the name of a type or an array of names
+	Type       interface{}        `json:"type"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	// AdditionalProperties is false, true or a schema, any value but
+	// false allows unknown keys
+	AdditionalProperties interface{} `json:"additionalProperties"`
+}
+
+// closed reports whether keys not in the properties are unknown
+func (s *schema) closed() bool {
+	if s.Properties == nil {
+		return false
+	}
+
+	allowed, ok := s.AdditionalProperties.(bool)
+	return s.AdditionalProperties == nil || ok && !allowed
+}
+
+// is reports whether the only type allowed by s is name
+func (s *schema) is(name string) bool {
+	if s == nil {
+		return false
+	}
+
+	if types, ok := s.Type.([]interface{}); ok && len(types) == 1 {
+		return types[0] == name
+	}
+
+	return s.Type == name
+}
+
+// property returns the schema of the key of an object described by s, nil
+// when it is unknown
+func (s *schema) property(key string) *schema {
+	if s == nil {
+		return nil
+	}
+
+	return s.Properties[key]
+}
+
+// items returns the schema of the elements of an array described by s, nil
+// when it is unknown
+func (s *schema) items() *schema {
+	if s == nil {
+		return nil
+	}
+
+	return s.Items
+}
+
+func parseSchema(jsonSchema []byte) (*schema, error) {
+	var root schema
+
+	err := json.Unmarshal(jsonSchema, &root)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse JSON schema")
+	}
+
+	return &root, nil
+}
+
+// UnknownKeys returns a rule reporting keys not described by the JSON
+// Schema, like the ones generated by cfg.JSONSchema. Objects without
+// properties, or with additionalProperties set, accept any key
+func UnknownKeys(jsonSchema []byte) (Rule, error) {
+	root, err := parseSchema(jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule{
+		name:        "unknown-keys",
+		description: "Keys not described by the schema, likely typos or removed settings.",
+		check: func(f *File) []Finding {
+			var findings []Finding
+
+			unknownKeys(f.Root, root, "", &findings)
+			return findings
+		},
+	}, nil
+}
+
+// SuspiciousBools returns the suspicious-bool rule of Rules fixing the
+// values of the keys the JSON Schema describes as booleans, which are
+// decoded the same once fixed. Values of other keys are only reported
+func SuspiciousBools(jsonSchema []byte) (Rule, error) {
+	root, err := parseSchema(jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return suspiciousBools(root), nil
+}
+
+func unknownKeys(n *cfg.Node, s *schema, prefix string, findings *[]Finding) {
+	if s == nil {
+		return
+	}
+
+	for _, item := range n.Items {
+		unknownKeys(item, s.Items, prefix, findings)
+	}
+
+	for _, e := range n.Entries {
+		path := joinPath(prefix, e.Key)
+		property, ok := s.Properties[e.Key]
+
+		if !ok && s.closed() && !(len(prefix) == 0 && e.Key == includeKey) {
+			msg := fmt.Sprintf("unknown key %s", path)
+
+			if suggestion := closest(e.Key, s.Properties); len(suggestion) > 0 {
+				msg += fmt.Sprintf(", did you mean %s?", suggestion)
+			}
+
+			*findings = append(*findings, Finding{Pos: e.Pos, Severity: Error, Msg: msg})
+			continue
+		}
+
+		unknownKeys(e.Value, property, path, findings)
+	}
+}
+
+// closest returns the property closest to key, when it is close enough to be a typo
+func closest(key string, properties map[string]*schema) string {
+	best, bestDistance := "", 3
+
+	for name := range properties {
+		d := distance(key, name)
+
+		if d < bestDistance || d == bestDistance && len(best) > 0 && name < best {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b, ignoring case
+func distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		previous := row[0]
+		row[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+
+			if lower(ra[i-1]) == lower(rb[j-1]) {
+				cost = 0
+			}
+
+			current := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, previous+cost)
+			previous = current
+		}
+	}
+
+	return row[len(rb)]
+}
+
+func lower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+
+	return r
+}
+
+func min(values ...int) int {
+	m := values[0]
+
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
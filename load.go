@@ -0,0 +1,89 @@
+package cfg
+
+import (
+	"flag"
+)
+
+// LoadOptions configures Load
+type LoadOptions struct {
+	// Path is the config file, no file is read when empty.
+	// It can include other files, see LoadFilesFS
+	Path string
+	// FS is the file system the file is read from, OSFS when nil
+	FS FS
+	// Limits restricts the resources used to decode the file, MaxBytes
+	// applies to each file and MaxKeys to all the included files together
+	Limits Limits
+	// Interpolate enables variables in values, see Decoder.Interpolate
+	Interpolate bool
+	// EnvPrefix enables the automatic environment variable names, see ApplyEnv
+	EnvPrefix string
+	// Lookup resolves variables and environment overrides, os.LookupEnv when nil
+	Lookup LookupFunc
+	// FlagSet is a parsed flag set bound to the same value with BindFlags
+	FlagSet *flag.FlagSet
+}
+
+// Load populates the struct pointed by v from the sources in opts, in order
+// of precedence: the current values of v act as defaults, then the file,
+// then the environment variables and finally the flags explicitly set.
+// Arrays in the file replace the default slices, unless the field uses
+// merge=append. The result is validated, see Validate
+func Load(v interface{}, opts LoadOptions) error {
+	d := decodeState{
+		limits:      opts.Limits,
+		md:          validationMetadata(v),
+		interpolate: opts.Interpolate,
+		lookup:      opts.Lookup,
+		// arrays in the file replace the defaults, like env values and flags
+		merge: true,
+	}
+
+	if len(opts.Path) > 0 {
+		err := newLoader(opts.FS, d).loadFiles(v, opts.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := applyEnvOverrides(v, opts.EnvPrefix, opts.Lookup, d.md)
+	if err != nil {
+		return err
+	}
+
+	if opts.FlagSet != nil {
+		err = applyFlags(opts.FlagSet, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.md != nil {
+		d.md.finish(v)
+	}
+
+	return validate(v, d.md)
+}
+
+// LoadFile reads the file at path and decodes it into the struct pointed by v,
+// following its includes, see LoadFilesFS, and validates it. Errors are
+// prefixed with the path
+func LoadFile(path string, v interface{}) error {
+	return LoadFileFS(OSFS{}, path, v)
+}
+
+// LoadFileFS works like LoadFile reading the file from fsys
+func LoadFileFS(fsys FS, path string, v interface{}) error {
+	if _, err := decodeTarget(v); err != nil {
+		return &FileError{Chain: []string{path}, Err: err}
+	}
+
+	d := decodeState{md: validationMetadata(v)}
+
+	err := newLoader(fsys, d).loadFiles(v, path)
+	if err != nil {
+		return err
+	}
+
+	return validate(v, d.md)
+}
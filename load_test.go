@@ -0,0 +1,225 @@
+package cfg
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.cfg")
+
+	err = ioutil.WriteFile(path, []byte(`name: file
+port: 7788
+players: 128
+resources: [a, b]
+voice: {
+  bitrate: 64000
+}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := struct {
+		Name        string   `cfg:"name"`
+		Port        int      `cfg:"port" env:"ALTV_PORT"`
+		Players     int      `cfg:"players"`
+		Description string   `cfg:"description"`
+		Resources   []string `cfg:"resources"`
+		Voice       struct {
+			BitRate int `cfg:"bitrate"`
+		} `cfg:"voice"`
+	}{
+		Name:        "default",
+		Description: "default",
+		Resources:   []string{"default"},
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	err = BindFlags(fs, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fs.Parse([]string{"-port", "9000", "-voice.bitrate", "128000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"ALTV_PORT":    "8000",
+		"ALTV_PLAYERS": "256",
+	}
+
+	err = Load(&v, LoadOptions{
+		Path:      path,
+		EnvPrefix: "ALTV",
+		Lookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		FlagSet: fs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// description from defaults, name and resources from the file,
+	// players from the environment and port and bitrate from flags
+	expected := []interface{}{"default", "file", []string{"a", "b"}, 256, 9000, 128000}
+	got := []interface{}{v.Description, v.Name, v.Resources, v.Players, v.Port, v.Voice.BitRate}
+
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("wrong precedence, expected %v, got %v", expected, got)
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		err := Load(&v, LoadOptions{Path: filepath.Join(dir, "missing.cfg")})
+		if err == nil {
+			t.Fatal("expected error for missing file")
+		}
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	v := struct {
+		Name  string `cfg:"name"`
+		Port  int    `cfg:"port"`
+		Voice struct {
+			BitRate int `cfg:"bitrate"`
+		} `cfg:"voice"`
+	}{}
+
+	fs := MapFS{
+		"server.cfg": []byte("name: test\nport: 7788\nvoice: {\n  bitrate: 64000\n}"),
+		"broken.cfg": []byte("name: test\nvoice: {\n  bitrate: [1]\n}"),
+	}
+
+	err := LoadFileFS(fs, "server.cfg", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "test" || v.Port != 7788 || v.Voice.BitRate != 64000 {
+		t.Fatalf("wrong value loaded, got %+v", v)
+	}
+
+	cases := []struct {
+		path   string
+		target interface{}
+	}{
+		{"broken.cfg", &v},
+		{"missing.cfg", &v},
+		{"server.cfg", v},
+	}
+
+	for _, c := range cases {
+		err := LoadFileFS(fs, c.path, c.target)
+		if err == nil || !strings.HasPrefix(err.Error(), c.path+": ") {
+			t.Fatalf("expected error prefixed with %s, got %v", c.path, err)
+		}
+	}
+
+	t.Run("os", func(t *testing.T) {
+		err := LoadFile(filepath.Join("example", "example.cfg"), &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Voice.BitRate != 64000 {
+			t.Fatalf("wrong value loaded, got %+v", v)
+		}
+	})
+}
+
+func TestLoadLimits(t *testing.T) {
+	type target struct {
+		Name string `cfg:"name"`
+		Port int    `cfg:"port"`
+	}
+
+	fs := MapFS{
+		"server.cfg":    []byte("name: 'TestServer'\ninclude: more.cfg"),
+		"more.cfg":      []byte("port: 7788\nplayers: 128"),
+		"large.cfg":     []byte("name: 'a very long server name'"),
+		"includes.cfg":  []byte("include: large.cfg"),
+		"duplicate.cfg": []byte("name: a\nname: b\nname: c"),
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		limits Limits
+		limit  string
+		line   int
+		chain  []string
+	}{
+		{
+			name:   "max bytes",
+			path:   "large.cfg",
+			limits: Limits{MaxBytes: 5},
+			limit:  "MaxBytes",
+			chain:  []string{"large.cfg"},
+		},
+		{
+			name:   "max bytes of an included file",
+			path:   "includes.cfg",
+			limits: Limits{MaxBytes: 20},
+			limit:  "MaxBytes",
+			chain:  []string{"includes.cfg", "large.cfg"},
+		},
+		{
+			name:   "max keys across includes",
+			path:   "server.cfg",
+			limits: Limits{MaxKeys: 3},
+			limit:  "MaxKeys",
+			line:   2,
+			chain:  []string{"server.cfg", "more.cfg"},
+		},
+		{
+			name:   "max keys of a file",
+			path:   "duplicate.cfg",
+			limits: Limits{MaxKeys: 2},
+			limit:  "MaxKeys",
+			line:   3,
+			chain:  []string{"duplicate.cfg"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v target
+
+			err := Load(&v, LoadOptions{Path: test.path, FS: fs, Limits: test.limits})
+
+			fileErr, ok := err.(*FileError)
+			if !ok {
+				t.Fatalf("expected *FileError, got %v", err)
+			}
+
+			limitErr, ok := fileErr.Err.(*LimitError)
+			if !ok || limitErr.Limit != test.limit || limitErr.Line != test.line || !reflect.DeepEqual(fileErr.Chain, test.chain) {
+				t.Fatalf("expected %s on line %d of %v, got %v", test.limit, test.line, test.chain, err)
+			}
+		})
+	}
+
+	var v target
+
+	err := Load(&v, LoadOptions{Path: "server.cfg", FS: fs, Limits: Limits{MaxBytes: 40, MaxKeys: 4}})
+	if err != nil {
+		t.Fatalf("expected the files to be within the limits, got %v", err)
+	}
+}
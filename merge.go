@@ -0,0 +1,72 @@
+package cfg
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Source is a named CFG input
+type Source struct {
+	// Name identifies the input on errors and metadata, like a file path
+	Name string
+	Data []byte
+}
+
+// Merge decodes the sources in order into the struct pointed by v, each
+// source is merged on top of the previous ones: scalars override, inner
+// structs merge key by key and arrays replace the slice, or append to it
+// when the field is tagged with merge=append, like `cfg:"resources,merge=append"`.
+//
+// The returned metadata records every merge decision, see Metadata.Merges
+func Merge(v interface{}, sources ...Source) (*Metadata, error) {
+	d := decodeState{md: newMetadata(), merge: true}
+	d.md.merging = true
+
+	for _, source := range sources {
+		d.md.source = source.Name
+
+		err := d.unmarshal(source.Data, v)
+		if err != nil {
+			return nil, errors.Wrap(err, source.Name)
+		}
+	}
+
+	d.md.finish(v)
+
+	err := validate(v, d.md)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.md, nil
+}
+
+// LoadFiles reads the files and merges them in order into the struct
+// pointed by v, see Merge. Files can include other files with the top level
+// include key, see LoadFilesFS
+func LoadFiles(v interface{}, paths ...string) (*Metadata, error) {
+	return LoadFilesFS(OSFS{}, v, paths...)
+}
+
+// LoadFilesFS works like LoadFiles reading the files from fsys.
+//
+// A file can include other files with a top level include key, holding a
+// path or an array of paths, like include: ["resources.cfg", "conf.d/*.cfg"].
+// Relative paths are relative to the including file and paths can be glob
+// patterns, matched in lexical order, a pattern matching no files is ignored.
+// Included files are merged at the position of the include key
+func LoadFilesFS(fsys FS, v interface{}, paths ...string) (*Metadata, error) {
+	d := decodeState{md: newMetadata(), merge: true}
+	d.md.merging = true
+
+	err := newLoader(fsys, d).loadFiles(v, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validate(v, d.md)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.md, nil
+}
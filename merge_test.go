@@ -0,0 +1,135 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mergeTarget struct {
+	Name      string   `cfg:"name"`
+	Port      int      `cfg:"port"`
+	Modules   []string `cfg:"modules"`
+	Resources []string `cfg:"resources,merge=append"`
+	Voice     struct {
+		BitRate      int    `cfg:"bitrate"`
+		ExternalHost string `cfg:"externalHost"`
+	} `cfg:"voice"`
+}
+
+const mergeBase = `name: base
+port: 7788
+modules: [node-module]
+resources: [chat, freeroam]
+voice: {
+  bitrate: 64000
+  externalHost: localhost
+}`
+
+const mergeProd = `port: 80
+modules: [csharp-module]
+resources: [anticheat]
+voice: {
+  externalHost: voice.example.com
+}`
+
+func TestMerge(t *testing.T) {
+	var v mergeTarget
+
+	md, err := Merge(&v, Source{Name: "base.cfg", Data: []byte(mergeBase)}, Source{Name: "prod.cfg", Data: []byte(mergeProd)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "base" || v.Port != 80 {
+		t.Fatalf("wrong scalars merged, got %+v", v)
+	}
+
+	if !reflect.DeepEqual(v.Modules, []string{"csharp-module"}) {
+		t.Fatalf("expected modules to be replaced, got %v", v.Modules)
+	}
+
+	if !reflect.DeepEqual(v.Resources, []string{"chat", "freeroam", "anticheat"}) {
+		t.Fatalf("expected resources to be appended, got %v", v.Resources)
+	}
+
+	if v.Voice.BitRate != 64000 || v.Voice.ExternalHost != "voice.example.com" {
+		t.Fatalf("expected voice to be merged, got %+v", v.Voice)
+	}
+
+	if md.Source("port") != "prod.cfg" || md.Source("name") != "base.cfg" {
+		t.Fatalf("wrong sources, got port from %q and name from %q", md.Source("port"), md.Source("name"))
+	}
+
+	var prod []string
+
+	for _, m := range md.Merges() {
+		if m.Source == "prod.cfg" {
+			prod = append(prod, m.Path+" "+string(m.Action))
+		}
+	}
+
+	expected := []string{
+		"port override",
+		"modules replace",
+		"resources append",
+		"voice.externalHost override",
+	}
+
+	if !reflect.DeepEqual(prod, expected) {
+		t.Fatalf("wrong merge records, expected %v, got %v", expected, prod)
+	}
+
+	t.Run("error names the source", func(t *testing.T) {
+		_, err := Merge(&v, Source{Name: "broken.cfg", Data: []byte("port: [1]")})
+		if err == nil || !strings.HasPrefix(err.Error(), "broken.cfg") {
+			t.Fatalf("expected error prefixed with the source name, got %v", err)
+		}
+	})
+}
+
+func TestUnmarshalAppendsSlices(t *testing.T) {
+	v := mergeTarget{Modules: []string{"default"}}
+
+	err := Unmarshal([]byte("modules: [a]\nmodules: [b]"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v.Modules, []string{"default", "a", "b"}) {
+		t.Fatalf("wrong slice decoded, got %v", v.Modules)
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.cfg")
+	prod := filepath.Join(dir, "server.prod.cfg")
+
+	for path, data := range map[string]string{base: mergeBase, prod: mergeProd} {
+		err := ioutil.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var v mergeTarget
+
+	md, err := LoadFiles(&v, base, prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Port != 80 || md.Source("voice.externalHost") != prod {
+		t.Fatalf("wrong value loaded, got %+v", v)
+	}
+}
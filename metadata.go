@@ -0,0 +1,185 @@
+package cfg
+
+import (
+	"reflect"
+)
+
+// MergeAction is how a key changed the decoded value
+type MergeAction string
+
+const (
+	// MergeSet is a field set for the first time
+	MergeSet MergeAction = "set"
+	// MergeOverride is a field that replaced the value set by a previous source
+	MergeOverride MergeAction = "override"
+	// MergeReplace is a slice that replaced the elements set by a previous source
+	MergeReplace MergeAction = "replace"
+	// MergeAppend is a slice that appended to the elements set by a previous source
+	MergeAppend MergeAction = "append"
+)
+
+// MergeRecord describes how a key from a source changed the decoded value
+type MergeRecord struct {
+	Path   string
+	Source string
+	Pos    Position
+	Action MergeAction
+}
+
+// Metadata describes how an input was decoded into a struct
+type Metadata struct {
+	keys      []string
+	undecoded []string
+	unset     []string
+	merges    []MergeRecord
+	positions map[string]Position
+	sources   map[string]string
+	set       map[string]bool
+	// source is the name of the input being decoded
+	source string
+	// merging enables the merge records
+	merging bool
+}
+
+func newMetadata() *Metadata {
+	return &Metadata{
+		positions: make(map[string]Position),
+		sources:   make(map[string]string),
+		set:       make(map[string]bool),
+	}
+}
+
+// Keys returns the path of every key decoded into a field, in input order.
+// Paths of inner struct keys are joined with a dot, like voice.externalPort
+func (md *Metadata) Keys() []string {
+	return md.keys
+}
+
+// Undecoded returns the path of every key that matched no field, in input order
+func (md *Metadata) Undecoded() []string {
+	return md.undecoded
+}
+
+// Unset returns the path of every field that the input did not set,
+// inner structs are described by their fields
+func (md *Metadata) Unset() []string {
+	return md.unset
+}
+
+// Position returns the position of the key with the given path,
+// the last one when the key is repeated
+func (md *Metadata) Position(path string) (Position, bool) {
+	pos, ok := md.positions[path]
+	return pos, ok
+}
+
+// Source returns the name of the source where the key with the given path
+// was last found, empty when decoding a single input
+func (md *Metadata) Source(path string) string {
+	return md.sources[path]
+}
+
+// Merges returns how each key of each source changed the decoded value,
+// in decoding order. It is only available from Merge and LoadFiles
+func (md *Metadata) Merges() []MergeRecord {
+	return md.merges
+}
+
+func (md *Metadata) addKey(path string, pos Position, decoded bool) {
+	if _, seen := md.positions[path]; !seen {
+		if decoded {
+			md.keys = append(md.keys, path)
+		} else {
+			md.undecoded = append(md.undecoded, path)
+		}
+	}
+
+	md.positions[path] = pos
+	md.sources[path] = md.source
+}
+
+// addPositions records the position of the keys inside n, used for
+// inner structs that are not decoded
+func (md *Metadata) addPositions(n *Node, prefix string) {
+	for _, e := range n.Entries {
+		path := joinPath(prefix, e.Key)
+		md.positions[path] = e.Pos
+		md.sources[path] = md.source
+		md.addPositions(e.Value, path)
+	}
+}
+
+// setField records that the field with the given path was set, action
+// is used when the field was already set by a previous source
+func (md *Metadata) setField(path string, pos Position, action MergeAction) {
+	if !md.set[path] {
+		action = MergeSet
+	}
+
+	md.set[path] = true
+
+	if md.merging {
+		md.merges = append(md.merges, MergeRecord{Path: path, Source: md.source, Pos: pos, Action: action})
+	}
+}
+
+// markSet records that the field with the given path was set outside of
+// the inputs, like by an environment variable
+func (md *Metadata) markSet(path string) {
+	md.set[path] = true
+}
+
+// finish collects the fields of v that were not set
+func (md *Metadata) finish(v interface{}) {
+	md.unset = nil
+	md.collectUnset(reflect.TypeOf(v).Elem(), "")
+}
+
+func (md *Metadata) collectUnset(t reflect.Type, prefix string) {
+	plan := planOf(t)
+
+	for _, f := range plan.fields {
+		if f.tag == "-" || !f.exported {
+			continue
+		}
+
+		path := joinPath(prefix, f.tag)
+
+		if f.isInner {
+			md.collectUnset(t.Field(f.index).Type, path)
+			continue
+		}
+
+		if !md.set[path] {
+			md.unset = append(md.unset, path)
+		}
+	}
+}
+
+func joinPath(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// DecodeWithMetadata works like Unmarshal and also returns the
+// metadata of the decoding
+func DecodeWithMetadata(data []byte, v interface{}) (*Metadata, error) {
+	d := decodeState{md: newMetadata()}
+
+	err := d.unmarshal(data, v)
+	if err != nil {
+		return nil, err
+	}
+
+	d.md.finish(v)
+
+	err = validate(v, d.md)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.md, nil
+}
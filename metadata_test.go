@@ -0,0 +1,76 @@
+package cfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWithMetadata(t *testing.T) {
+	v := struct {
+		Name    string   `cfg:"name"`
+		Port    int      `cfg:"port"`
+		Ignored string   `cfg:"-"`
+		Modules []string `cfg:"modules"`
+		Voice   struct {
+			BitRate      int    `cfg:"bitrate"`
+			ExternalHost string `cfg:"externalHost"`
+		} `cfg:"voice"`
+	}{}
+
+	md, err := DecodeWithMetadata([]byte(`name: test
+unknown: value
+modules: [a]
+voice: {
+  bitrate: 64000
+  extra: {
+    value: 1
+  }
+}`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(md.Keys(), []string{"name", "modules", "voice", "voice.bitrate"}) {
+		t.Fatalf("wrong keys, got %v", md.Keys())
+	}
+
+	if !reflect.DeepEqual(md.Undecoded(), []string{"unknown", "voice.extra"}) {
+		t.Fatalf("wrong undecoded keys, got %v", md.Undecoded())
+	}
+
+	if !reflect.DeepEqual(md.Unset(), []string{"port", "voice.externalHost"}) {
+		t.Fatalf("wrong unset fields, got %v", md.Unset())
+	}
+
+	pos, ok := md.Position("voice.bitrate")
+	if !ok || pos != (Position{Line: 5, Column: 3}) {
+		t.Fatalf("wrong position, expected 5:3, got %s", pos)
+	}
+
+	pos, ok = md.Position("voice.extra.value")
+	if !ok || pos.Line != 7 {
+		t.Fatalf("wrong position, expected line 7, got %s", pos)
+	}
+
+	if _, ok := md.Position("port"); ok {
+		t.Fatal("expected no position for port")
+	}
+}
+
+func TestDecoderMetadata(t *testing.T) {
+	v := struct {
+		Name string `cfg:"name"`
+	}{}
+
+	dec := NewDecoder(strings.NewReader("name: test\nother: value"))
+
+	err := dec.Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(dec.Metadata().Undecoded(), []string{"other"}) {
+		t.Fatalf("wrong undecoded keys, got %v", dec.Metadata().Undecoded())
+	}
+}
@@ -0,0 +1,652 @@
+package cfg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// maxNesting bounds the recursion of the parser, it applies even when
+// no Limits are set so deeply nested input can't exhaust the stack
+const maxNesting = 10000
+
+// spaces are the characters skipped around keys and values, values are
+// trimmed of the same characters so formatting them does not change them
+const spaces = " \t\r"
+
+// Position is a location in the input, lines and columns start at 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SyntaxError is returned when the input is not valid CFG
+type SyntaxError struct {
+	Msg string
+	Pos Position
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("could not decode line %d, %s", e.Pos.Line, e.Msg)
+}
+
+// NodeKind is the kind of a Node
+type NodeKind int
+
+const (
+	// ScalarNode is a single value, like a string, a number or a bool
+	ScalarNode NodeKind = iota
+	// ArrayNode is an array, its elements are in Items
+	ArrayNode
+	// ObjectNode is an inner struct or the whole input, its keys are in Entries
+	ObjectNode
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case ScalarNode:
+		return "scalar"
+	case ArrayNode:
+		return "array"
+	case ObjectNode:
+		return "inner struct"
+	}
+
+	return fmt.Sprintf("NodeKind(%d)", int(k))
+}
+
+// Node is a value of the parsed input
+type Node struct {
+	Kind NodeKind
+	Pos  Position
+
+	// Value is the scalar value without quotation marks
+	Value string
+	// Quote is the quotation mark used by the scalar, zero when unquoted
+	Quote byte
+
+	Items   []*Node
+	Entries []*Entry
+
+	// The comments are only recorded by ParseDocument, they hold the whole
+	// comment starting with #, blank lines are recorded as empty strings.
+
+	// Comments are the lines above an array element
+	Comments []string
+	// Comment is the comment after an array element, on the same line
+	Comment string
+	// OpenComment is the comment after the opening [ or {, on the same line
+	OpenComment string
+	// EndComments are the lines after the last element of an array,
+	// inner struct or the whole input
+	EndComments []string
+}
+
+// Entry is a key value pair of an object node
+type Entry struct {
+	Key   string
+	Pos   Position
+	Value *Node
+
+	// Comments are the lines above the key, only recorded by ParseDocument
+	Comments []string
+	// Comment is the comment after the value, on the same line, only
+	// recorded by ParseDocument
+	Comment string
+}
+
+type parser struct {
+	data      []byte
+	offset    int
+	line      int
+	lineStart int
+	limits    Limits
+	depth     int
+	nesting   int
+	keys      int
+	// comments enables recording comments and blank lines
+	comments bool
+}
+
+// Parse parses the data provided and returns the root object node
+func Parse(data []byte) (*Node, error) {
+	return parse(data, Limits{})
+}
+
+// ParseDocument works like Parse and also records the comments and blank
+// lines on the nodes, so the input can be formatted or edited keeping them
+func ParseDocument(data []byte) (*Node, error) {
+	p := parser{
+		data:     data,
+		line:     1,
+		comments: true,
+	}
+
+	return p.parse()
+}
+
+// parse walks the input once and returns the root object node
+func parse(data []byte, limits Limits) (*Node, error) {
+	p := parser{
+		data:   data,
+		line:   1,
+		limits: limits,
+	}
+
+	return p.parse()
+}
+
+func (p *parser) parse() (*Node, error) {
+	if err := p.checkLineLength(); err != nil {
+		return nil, err
+	}
+
+	root := &Node{Kind: ObjectNode, Pos: Position{Line: 1, Column: 1}}
+
+	err := p.parseEntries(root, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (p *parser) checkLineLength() error {
+	if p.limits.MaxLineLength <= 0 {
+		return nil
+	}
+
+	data := p.data
+
+	for line := 1; len(data) > 0; line++ {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data)
+		}
+
+		if end > p.limits.MaxLineLength {
+			return &LimitError{Limit: "MaxLineLength", Max: int64(p.limits.MaxLineLength), Line: line}
+		}
+
+		if end == len(data) {
+			break
+		}
+
+		data = data[end+1:]
+	}
+
+	return nil
+}
+
+func (p *parser) eof() bool {
+	return p.offset >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	return p.data[p.offset]
+}
+
+func (p *parser) next() {
+	if p.data[p.offset] == '\n' {
+		p.line++
+		p.lineStart = p.offset + 1
+	}
+
+	p.offset++
+}
+
+func (p *parser) pos() Position {
+	return Position{Line: p.line, Column: p.offset - p.lineStart + 1}
+}
+
+func (p *parser) errorf(pos Position, format string, args ...interface{}) error {
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Pos: pos}
+}
+
+// skipSpaces skips spaces on the current line
+func (p *parser) skipSpaces() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// skipComment skips a comment until the end of the line
+func (p *parser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// skipBlank skips spaces, new lines, comments and optionally commas
+func (p *parser) skipBlank(commas bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.next()
+		case c == '#':
+			p.skipComment()
+		case c == ',' && commas:
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// blank works like skipBlank, when recording comments it returns the
+// comment on the same line as the previous value and the comment and
+// blank lines after it
+func (p *parser) blank(commas bool) ([]string, string) {
+	if !p.comments {
+		p.skipBlank(commas)
+		return nil, ""
+	}
+
+	var lines []string
+	var trailing string
+
+	newlines := 0
+
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r' || (c == ',' && commas):
+			p.next()
+		case c == '\n':
+			p.next()
+			newlines++
+
+			if newlines > 1 {
+				lines = append(lines, "")
+			}
+		case c == '#':
+			start := p.offset
+			p.skipComment()
+
+			comment := strings.TrimRight(string(p.data[start:p.offset]), " \t\r")
+
+			// a comment after something on the same line belongs to it
+			if newlines == 0 && len(bytes.Trim(p.data[p.lineStart:start], spaces)) > 0 {
+				trailing = comment
+			} else {
+				lines = append(lines, comment)
+			}
+
+			newlines = 0
+		default:
+			return lines, trailing
+		}
+	}
+
+	return lines, trailing
+}
+
+func (p *parser) nest(pos Position) error {
+	p.nesting++
+
+	if p.nesting > maxNesting {
+		return p.errorf(pos, "nesting deeper than %d levels", maxNesting)
+	}
+
+	return nil
+}
+
+// parseEntries reads key value pairs into obj until the end of the input,
+// or until the closing curly brace when closing is true
+func (p *parser) parseEntries(obj *Node, closing bool) error {
+	for {
+		lines, trailing := p.blank(true)
+
+		switch {
+		case len(trailing) == 0:
+		case len(obj.Entries) > 0:
+			obj.Entries[len(obj.Entries)-1].Comment = trailing
+		case closing:
+			obj.OpenComment = trailing
+		default:
+			lines = append([]string{trailing}, lines...)
+		}
+
+		if p.eof() || p.peek() == '}' {
+			obj.EndComments = lines
+		}
+
+		if p.eof() {
+			if closing {
+				return p.errorf(p.pos(), "expected } to close inner struct started on line %d", obj.Pos.Line)
+			}
+
+			return nil
+		}
+
+		if p.peek() == '}' {
+			if closing {
+				p.next()
+				return nil
+			}
+
+			return p.errorf(p.pos(), "unexpected }")
+		}
+
+		e, err := p.parseEntry()
+		if err != nil {
+			return err
+		}
+
+		if len(lines) > 0 {
+			e.Comments = append(lines, e.Comments...)
+		}
+
+		obj.Entries = append(obj.Entries, e)
+	}
+}
+
+func (p *parser) parseEntry() (*Entry, error) {
+	e := &Entry{Pos: p.pos()}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+
+	e.Key = key
+	p.keys++
+
+	if p.limits.MaxKeys > 0 && p.keys > p.limits.MaxKeys {
+		return nil, &LimitError{Limit: "MaxKeys", Max: int64(p.limits.MaxKeys), Line: e.Pos.Line}
+	}
+
+	p.skipSpaces()
+
+	// the key is the only thing on the line, the value is on the next ones
+	valueOnNextLine := p.eof() || p.peek() == '\n' || p.peek() == '#'
+
+	if valueOnNextLine {
+		lines, trailing := p.blank(false)
+
+		// comments between the key and the value are kept above the key
+		if len(trailing) > 0 {
+			lines = append([]string{trailing}, lines...)
+		}
+
+		e.Comments = lines
+
+		if p.eof() {
+			return nil, p.errorf(e.Pos, "expected value for key %q", key)
+		}
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if valueOnNextLine && value.Kind == ScalarNode && value.Quote == 0 && strings.Contains(value.Value, ":") {
+		return nil, p.errorf(e.Pos, "expected value for key %q", key)
+	}
+
+	e.Value = value
+	return e, nil
+}
+
+func (p *parser) parseKey() (string, error) {
+	pos := p.pos()
+
+	var key string
+
+	if c := p.peek(); c == '\'' || c == '"' {
+		value, err := p.parseQuoted()
+		if err != nil {
+			return "", err
+		}
+
+		key = value.Value
+		p.skipSpaces()
+	} else {
+		start := p.offset
+
+		for !p.eof() && !isKeyDelimiter(p.peek()) {
+			p.next()
+		}
+
+		key = strings.Trim(string(p.data[start:p.offset]), spaces)
+
+		if len(key) == 0 {
+			return "", p.errorf(pos, "expected key")
+		}
+	}
+
+	if p.eof() || p.peek() != ':' {
+		return "", p.errorf(pos, "expected : after key %q", key)
+	}
+
+	p.next()
+	return key, nil
+}
+
+func isKeyDelimiter(c byte) bool {
+	switch c {
+	case ':', '\n', '#', ',', '[', ']', '{', '}':
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) parseValue() (*Node, error) {
+	switch p.peek() {
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseObject()
+	case '\'', '"':
+		return p.parseQuoted()
+	}
+
+	return p.parseUnquoted(), nil
+}
+
+func (p *parser) parseArray() (*Node, error) {
+	n := &Node{Kind: ArrayNode, Pos: p.pos()}
+
+	if err := p.nest(n.Pos); err != nil {
+		return nil, err
+	}
+
+	p.next()
+
+	for {
+		lines, trailing := p.blank(true)
+
+		switch {
+		case len(trailing) == 0:
+		case len(n.Items) > 0:
+			n.Items[len(n.Items)-1].Comment = trailing
+		default:
+			n.OpenComment = trailing
+		}
+
+		if p.eof() {
+			return nil, p.errorf(p.pos(), "expected ] to close array started on line %d", n.Pos.Line)
+		}
+
+		if p.peek() == ']' {
+			n.EndComments = lines
+
+			// like the first parser, one line arrays are also separated by spaces
+			if p.pos().Line == n.Pos.Line {
+				n.Items = splitSpaces(n.Items)
+
+				if p.limits.MaxArrayLength > 0 && len(n.Items) > p.limits.MaxArrayLength {
+					return nil, &LimitError{Limit: "MaxArrayLength", Max: int64(p.limits.MaxArrayLength), Line: n.Pos.Line}
+				}
+			}
+
+			p.next()
+			break
+		}
+
+		if p.peek() == '}' {
+			return nil, p.errorf(p.pos(), "unexpected } inside array started on line %d", n.Pos.Line)
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		item.Comments = lines
+		n.Items = append(n.Items, item)
+
+		if p.limits.MaxArrayLength > 0 && len(n.Items) > p.limits.MaxArrayLength {
+			return nil, &LimitError{Limit: "MaxArrayLength", Max: int64(p.limits.MaxArrayLength), Line: item.Pos.Line}
+		}
+	}
+
+	p.nesting--
+	return n, nil
+}
+
+// splitSpaces returns items with the unquoted values split at the spaces
+// outside of variables, so [a b c] has three elements
+func splitSpaces(items []*Node) []*Node {
+	var split []*Node
+
+	for _, item := range items {
+		if item.Kind != ScalarNode || item.Quote != 0 || !strings.ContainsAny(item.Value, spaces) {
+			split = append(split, item)
+			continue
+		}
+
+		begin := len(split)
+		start, depth := -1, 0
+
+		for i := 0; i <= len(item.Value); i++ {
+			space := i == len(item.Value) || depth == 0 && strings.IndexByte(spaces, item.Value[i]) >= 0
+
+			switch {
+			case space && start >= 0:
+				split = append(split, &Node{
+					Kind:  ScalarNode,
+					Pos:   Position{Line: item.Pos.Line, Column: item.Pos.Column + start},
+					Value: item.Value[start:i],
+				})
+
+				start = -1
+			case space:
+			case strings.HasPrefix(item.Value[i:], "${"):
+				depth++
+			case item.Value[i] == '}' && depth > 0:
+				depth--
+			}
+
+			if !space && start < 0 {
+				start = i
+			}
+		}
+
+		// the comments of the value stay with its first and last parts
+		split[begin].Comments = item.Comments
+		split[len(split)-1].Comment = item.Comment
+	}
+
+	return split
+}
+
+func (p *parser) parseObject() (*Node, error) {
+	n := &Node{Kind: ObjectNode, Pos: p.pos()}
+
+	p.depth++
+
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		return nil, &LimitError{Limit: "MaxDepth", Max: int64(p.limits.MaxDepth), Line: n.Pos.Line}
+	}
+
+	if err := p.nest(n.Pos); err != nil {
+		return nil, err
+	}
+
+	p.next()
+
+	err := p.parseEntries(n, true)
+	if err != nil {
+		return nil, err
+	}
+
+	p.depth--
+	p.nesting--
+	return n, nil
+}
+
+func (p *parser) parseQuoted() (*Node, error) {
+	n := &Node{Kind: ScalarNode, Pos: p.pos(), Quote: p.peek()}
+
+	p.next()
+	start := p.offset
+
+	for !p.eof() && p.peek() != n.Quote {
+		if p.peek() == '\n' {
+			break
+		}
+
+		p.next()
+	}
+
+	if p.eof() || p.peek() != n.Quote {
+		return nil, p.errorf(n.Pos, "unterminated string")
+	}
+
+	n.Value = string(p.data[start:p.offset])
+	p.next()
+	return n, nil
+}
+
+// skipVariable skips a ${...} variable reference, so the closing curly
+// brace of the variable does not end the value
+func (p *parser) skipVariable() {
+	p.next()
+
+	if p.eof() || p.peek() != '{' {
+		return
+	}
+
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		p.next()
+
+		if c == '}' {
+			return
+		}
+	}
+}
+
+// parseUnquoted reads a value until the end of the line, a comma, a comment
+// or the end of the array or inner struct it belongs to
+func (p *parser) parseUnquoted() *Node {
+	n := &Node{Kind: ScalarNode, Pos: p.pos()}
+	start := p.offset
+
+loop:
+	for !p.eof() {
+		switch p.peek() {
+		case '\n', ',', '#', ']', '}':
+			break loop
+		case '$':
+			p.skipVariable()
+			continue
+		}
+
+		p.next()
+	}
+
+	n.Value = strings.Trim(string(p.data[start:p.offset]), spaces)
+	return n
+}
@@ -0,0 +1,219 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("complete example", func(t *testing.T) {
+		root, err := parse([]byte(completeExample), Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(root.Entries) != 18 {
+			t.Fatalf("wrong number of entries, expected 18, got %d", len(root.Entries))
+		}
+
+		voice := root.Entries[17]
+
+		if voice.Key != "voice" || voice.Value.Kind != ObjectNode {
+			t.Fatalf("wrong entry, expected voice object, got %q", voice.Key)
+		}
+
+		if voice.Pos.Line != 30 {
+			t.Fatalf("wrong line, expected 30, got %d", voice.Pos.Line)
+		}
+
+		port := voice.Value.Entries[2]
+
+		if port.Key != "externalPort" || port.Value.Value != "7798" {
+			t.Fatalf("wrong entry, expected externalPort: 7798, got %s: %s", port.Key, port.Value.Value)
+		}
+
+		if port.Pos.Line != 34 || port.Pos.Column != 3 {
+			t.Fatalf("wrong position, expected 34:3, got %s", port.Pos)
+		}
+	})
+
+	t.Run("one line arrays separated by spaces", func(t *testing.T) {
+		root, err := parse([]byte("a: [x y, 'z w' ${V:-1 2}]\nb: [\n  x y\n]"), Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+
+		for _, item := range root.Entries[0].Value.Items {
+			got = append(got, fmt.Sprintf("%s %s", item.Pos, item.Value))
+		}
+
+		expected := []string{"1:5 x", "1:7 y", "1:10 z w", "1:16 ${V:-1 2}"}
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %q, got %q", expected, got)
+		}
+
+		if items := root.Entries[1].Value.Items; len(items) != 1 || items[0].Value != "x y" {
+			t.Fatalf("expected multi line arrays to keep the spaces, got %v", root.Entries[1].Value.Interface())
+		}
+
+		_, err = parse([]byte("a: [x y z]"), Limits{MaxArrayLength: 2})
+		if _, ok := err.(*LimitError); !ok {
+			t.Fatalf("expected a limit error, got %v", err)
+		}
+	})
+
+	t.Run("quoted values", func(t *testing.T) {
+		root, err := parse([]byte(`a: 'single # not a comment', b: "double", c: bare value # comment`), Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []struct {
+			key   string
+			value string
+			quote byte
+		}{
+			{"a", "single # not a comment", '\''},
+			{"b", "double", '"'},
+			{"c", "bare value", 0},
+		}
+
+		for i, e := range expected {
+			got := root.Entries[i]
+
+			if got.Key != e.key || got.Value.Value != e.value || got.Value.Quote != e.quote {
+				t.Fatalf("wrong entry, expected %s: %q (%q), got %s: %q (%q)", e.key, e.value, e.quote, got.Key, got.Value.Value, got.Value.Quote)
+			}
+		}
+	})
+
+	t.Run("nested values", func(t *testing.T) {
+		root, err := parse([]byte("a: [[1, 2], {b: c}]\nd: {\n  e: {\n    f: [x]\n  }\n}"), Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a := root.Entries[0].Value
+
+		if len(a.Items) != 2 || a.Items[0].Kind != ArrayNode || a.Items[1].Kind != ObjectNode {
+			t.Fatal("wrong array decoded")
+		}
+
+		f := root.Entries[1].Value.Entries[0].Value.Entries[0]
+
+		if f.Key != "f" || f.Pos.Line != 4 || f.Value.Items[0].Value != "x" {
+			t.Fatal("wrong inner struct decoded")
+		}
+	})
+
+	t.Run("syntax errors", func(t *testing.T) {
+		cases := []struct {
+			input string
+			line  int
+		}{
+			{"a: 'unterminated", 1},
+			{"a: b\nc", 2},
+			{"a: b\n}", 2},
+			{"a: {\n b: c\n", 3},
+			{"a: [\n b\n", 3},
+			{"a: [\n }\n]", 2},
+			{"a:\nb: c", 1},
+			{"a:", 1},
+		}
+
+		for _, c := range cases {
+			_, err := parse([]byte(c.input), Limits{})
+
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("expected *SyntaxError for %q, got %v", c.input, err)
+			}
+
+			if syntaxErr.Pos.Line != c.line {
+				t.Fatalf("wrong line for %q, expected %d, got %d", c.input, c.line, syntaxErr.Pos.Line)
+			}
+		}
+	})
+
+	t.Run("nesting", func(t *testing.T) {
+		input := make([]byte, maxNesting+1)
+		for i := range input {
+			input[i] = '['
+		}
+
+		_, err := parse(append([]byte("a: "), input...), Limits{})
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Fatalf("expected *SyntaxError, got %v", err)
+		}
+	})
+}
+
+func TestParseDocument(t *testing.T) {
+	root, err := ParseDocument([]byte(`# header
+
+name: a # name
+list: [ # open
+  # first
+  1, # one
+
+  2
+  # end
+]
+voice: {
+  # inner
+  port: 1
+} # close
+# footer`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, list, voice := root.Entries[0], root.Entries[1].Value, root.Entries[2]
+
+	if !equalStrings(name.Comments, []string{"# header", ""}) || name.Comment != "# name" {
+		t.Fatalf("wrong name comments, got %q %q", name.Comments, name.Comment)
+	}
+
+	if list.OpenComment != "# open" || !equalStrings(list.EndComments, []string{"# end"}) {
+		t.Fatalf("wrong list comments, got %q %q", list.OpenComment, list.EndComments)
+	}
+
+	if !equalStrings(list.Items[0].Comments, []string{"# first"}) || list.Items[0].Comment != "# one" || !equalStrings(list.Items[1].Comments, []string{""}) {
+		t.Fatalf("wrong item comments, got %q %q %q", list.Items[0].Comments, list.Items[0].Comment, list.Items[1].Comments)
+	}
+
+	if voice.Comment != "# close" || !equalStrings(voice.Value.Entries[0].Comments, []string{"# inner"}) {
+		t.Fatalf("wrong voice comments, got %q %q", voice.Comment, voice.Value.Entries[0].Comments)
+	}
+
+	if !equalStrings(root.EndComments, []string{"# footer"}) {
+		t.Fatalf("wrong end comments, got %q", root.EndComments)
+	}
+
+	root, err = Parse([]byte("# header\nname: a # name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Entries[0].Comments != nil || root.Entries[0].Comment != "" {
+		t.Fatal("expected no comments from Parse")
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,238 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// setter converts value and stores it on v
+type setter func(v reflect.Value, value string) error
+
+// structPlan is the precompiled information needed to decode and encode
+// a struct type, plans are cached per type and shared by Unmarshal and Marshal
+type structPlan struct {
+	fields []fieldPlan
+	// byTag maps a key to the fields decoded from it
+	byTag map[string][]int
+	// validated is set when a field, or a field of an inner struct, has validate rules
+	validated bool
+	// rulesErr is the first invalid validate tag
+	rulesErr error
+}
+
+type fieldPlan struct {
+	tag      string
+	index    int
+	kind     reflect.Kind
+	isArray  bool
+	isInner  bool
+	exported bool
+	// append makes decoded arrays append to the slice instead of replacing it
+	append bool
+	// env is the environment variable from the env tag, - disables overrides
+	env string
+	// set stores a scalar value, or a single element for slices,
+	// nil when the type is not supported
+	set setter
+	// rules are the compiled rules of the validate tag
+	rules []rule
+}
+
+// field returns the field described by the plan on the struct value rv
+func (fp *fieldPlan) field(rv reflect.Value) field {
+	return field{
+		Tag:     fp.tag,
+		IsArray: fp.isArray,
+		IsInner: fp.isInner,
+		Kind:    fp.kind,
+		Value:   rv.Field(fp.index),
+		Append:  fp.append,
+		set:     fp.set,
+	}
+}
+
+var planCache sync.Map
+
+// planOf returns the cached plan of the struct type t, compiling it on first use
+func planOf(t reflect.Type) *structPlan {
+	if plan, ok := planCache.Load(t); ok {
+		return plan.(*structPlan)
+	}
+
+	plan, _ := planCache.LoadOrStore(t, compilePlan(t))
+	return plan.(*structPlan)
+}
+
+func compilePlan(t reflect.Type) *structPlan {
+	plan := &structPlan{
+		fields: make([]fieldPlan, 0, t.NumField()),
+		byTag:  make(map[string][]int, t.NumField()),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		fieldTag, options := parseTag(fieldType.Tag.Get(tagName))
+		fieldKind := fieldType.Type.Kind()
+
+		if len(fieldTag) == 0 {
+			fieldTag = fieldType.Name
+		}
+
+		fp := fieldPlan{
+			tag:      fieldTag,
+			index:    i,
+			kind:     fieldKind,
+			isArray:  fieldKind == reflect.Slice,
+			isInner:  fieldKind == reflect.Struct,
+			exported: fieldType.PkgPath == "",
+			append:   options["merge"] == "append",
+			env:      strings.TrimSpace(fieldType.Tag.Get(envTagName)),
+		}
+
+		if fp.isArray {
+			fp.set = scalarSetter(fieldType.Type.Elem())
+		} else {
+			fp.set = scalarSetter(fieldType.Type)
+		}
+
+		compileRules(plan, t, &fp, fieldType)
+
+		plan.fields = append(plan.fields, fp)
+
+		if fp.tag != "-" && fp.exported {
+			plan.byTag[fp.tag] = append(plan.byTag[fp.tag], len(plan.fields)-1)
+		}
+	}
+
+	if reflect.PtrTo(t).Implements(validatorType) {
+		plan.validated = true
+	}
+
+	return plan
+}
+
+// compileRules compiles the validate tag of the field described by fp
+func compileRules(plan *structPlan, t reflect.Type, fp *fieldPlan, fieldType reflect.StructField) {
+	if fp.tag == "-" || !fp.exported {
+		return
+	}
+
+	if fp.isInner {
+		inner := planOf(fieldType.Type)
+		plan.validated = plan.validated || inner.validated
+
+		if plan.rulesErr == nil {
+			plan.rulesErr = inner.rulesErr
+		}
+	}
+
+	tag := strings.TrimSpace(fieldType.Tag.Get(validateTagName))
+	if len(tag) == 0 {
+		return
+	}
+
+	plan.validated = true
+
+	var err error
+
+	switch {
+	case fp.isInner:
+		err = fmt.Errorf("rules are not supported on inner structs")
+	case fp.isArray:
+		fp.rules, err = parseRules(tag, fieldType.Type.Elem(), true, t)
+	default:
+		fp.rules, err = parseRules(tag, fieldType.Type, false, t)
+	}
+
+	if err != nil && plan.rulesErr == nil {
+		plan.rulesErr = fmt.Errorf("invalid validate tag on field %s, %s", fieldType.Name, err)
+	}
+}
+
+// parseTag splits a tag like "resources,merge=append" into its
+// name and options, options without a value are set to "true"
+func parseTag(tag string) (string, map[string]string) {
+	parts := strings.Split(tag, ",")
+	options := make(map[string]string, len(parts)-1)
+
+	for _, option := range parts[1:] {
+		option = strings.TrimSpace(option)
+
+		if len(option) == 0 {
+			continue
+		}
+
+		value := "true"
+
+		if sep := strings.Index(option, "="); sep >= 0 {
+			value = strings.TrimSpace(option[sep+1:])
+			option = strings.TrimSpace(option[0:sep])
+		}
+
+		options[option] = value
+	}
+
+	return strings.TrimSpace(parts[0]), options
+}
+
+// scalarSetter returns the setter for values of type t, nil when t is not a scalar
+func scalarSetter(t reflect.Type) setter {
+	switch t.Kind() {
+	case reflect.String:
+		return setString
+	case reflect.Bool:
+		return setBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return setInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setUint
+	case reflect.Float32, reflect.Float64:
+		return setFloat
+	}
+
+	return nil
+}
+
+func setString(v reflect.Value, value string) error {
+	v.SetString(value)
+	return nil
+}
+
+func setBool(v reflect.Value, value string) error {
+	v.SetBool(boolValue(value))
+	return nil
+}
+
+func setInt(v reflect.Value, value string) error {
+	n, err := intValue(value)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, v.Kind()))
+	}
+
+	v.SetInt(n)
+	return nil
+}
+
+func setUint(v reflect.Value, value string) error {
+	n, err := uintValue(value)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, v.Kind()))
+	}
+
+	v.SetUint(n)
+	return nil
+}
+
+func setFloat(v reflect.Value, value string) error {
+	n, err := floatValue(value, v.Type().Bits())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not convert %q to %q", value, v.Kind()))
+	}
+
+	v.SetFloat(n)
+	return nil
+}
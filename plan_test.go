@@ -0,0 +1,76 @@
+package cfg
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPlanOf(t *testing.T) {
+	type target struct {
+		Name    string `cfg:"name"`
+		Ignored string `cfg:"-"`
+		hidden  string
+		Ports   []uint16 `cfg:"ports"`
+		Voice   struct {
+			Host string `cfg:"host"`
+		} `cfg:"voice"`
+		Other map[string]string `cfg:"other"`
+	}
+
+	typ := reflect.TypeOf(target{})
+
+	t.Run("cached", func(t *testing.T) {
+		var wg sync.WaitGroup
+		plans := make([]*structPlan, 10)
+
+		for i := range plans {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				plans[i] = planOf(typ)
+			}(i)
+		}
+
+		wg.Wait()
+
+		for _, plan := range plans {
+			if plan != planOf(typ) {
+				t.Fatal("expected the same plan for the same type")
+			}
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		plan := planOf(typ)
+
+		if len(plan.fields) != 6 {
+			t.Fatalf("wrong number of fields, expected 6, got %d", len(plan.fields))
+		}
+
+		for _, key := range []string{"-", "hidden"} {
+			if _, ok := plan.byTag[key]; ok {
+				t.Fatalf("expected %q to not be decoded", key)
+			}
+		}
+
+		ports := plan.fields[plan.byTag["ports"][0]]
+
+		if !ports.isArray || ports.set == nil {
+			t.Fatal("expected ports to be a slice with a setter")
+		}
+
+		voice := plan.fields[plan.byTag["voice"][0]]
+
+		if !voice.isInner {
+			t.Fatal("expected voice to be an inner struct")
+		}
+
+		other := plan.fields[plan.byTag["other"][0]]
+
+		if other.set != nil {
+			t.Fatal("expected no setter for map types")
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package cfg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PathError is returned when a key path can't be resolved
+type PathError struct {
+	// Path is the requested path
+	Path string
+	// Segment is the path up to the segment that could not be resolved
+	Segment string
+	Msg     string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("could not resolve %q, %s %s", e.Path, e.Segment, e.Msg)
+}
+
+// Lookup returns the node at path, a dot separated list of keys where array
+// elements are selected by index, like voice.externalPort or tags[2].
+// Repeated keys are resolved like Unmarshal does: inner structs are merged
+// and arrays appended, otherwise the last one is used
+func (n *Node) Lookup(path string) (*Node, error) {
+	nodes, err := n.resolve(path, strings.Split(path, "."))
+	if err != nil {
+		return nil, err
+	}
+
+	return merged(nodes), nil
+}
+
+// resolve returns the values at the path segments of path, more than one
+// when they are repeated inner structs or arrays, see keyValues
+func (n *Node) resolve(path string, segments []string) ([]*Node, error) {
+	current := []*Node{n}
+	resolved := ""
+
+	for _, segment := range segments {
+		key, indexes, ok := splitIndexes(segment)
+		if !ok {
+			return nil, &PathError{Path: path, Segment: joinPath(resolved, segment), Msg: "is not a valid path"}
+		}
+
+		parent := resolved
+		resolved = joinPath(resolved, key)
+
+		if current[0].Kind != ObjectNode {
+			return nil, &PathError{Path: path, Segment: resolved, Msg: fmt.Sprintf("not found, %s is not an inner struct", parent)}
+		}
+
+		current = keyValues(current, key)
+		if len(current) == 0 {
+			return nil, &PathError{Path: path, Segment: resolved, Msg: "not found"}
+		}
+
+		for _, index := range indexes {
+			array, i, err := element(current, path, resolved, index)
+			if err != nil {
+				return nil, err
+			}
+
+			current = []*Node{array.Items[i]}
+			resolved = fmt.Sprintf("%s[%d]", resolved, index)
+		}
+	}
+
+	return current, nil
+}
+
+// element returns the array of nodes holding the element index, counting
+// the elements of all of them like Unmarshal appends them, and its index
+// in that array. resolved is the part of path nodes are at
+func element(nodes []*Node, path string, resolved string, index int) (*Node, int, error) {
+	if nodes[0].Kind != ArrayNode {
+		return nil, 0, &PathError{Path: path, Segment: fmt.Sprintf("%s[%d]", resolved, index), Msg: fmt.Sprintf("not found, %s is not an array", resolved)}
+	}
+
+	count := 0
+
+	for _, array := range nodes {
+		if index < count+len(array.Items) {
+			return array, index - count, nil
+		}
+
+		count += len(array.Items)
+	}
+
+	return nil, 0, &PathError{Path: path, Segment: fmt.Sprintf("%s[%d]", resolved, index), Msg: fmt.Sprintf("not found, %s has %d elements", resolved, count)}
+}
+
+// Has reports whether path exists
+func (n *Node) Has(path string) bool {
+	_, err := n.Lookup(path)
+	return err == nil
+}
+
+// Interface returns the value of the node as a string for scalars,
+// []interface{} for arrays and map[string]interface{} for inner structs.
+// Repeated keys are merged like Unmarshal does, see keyValues
+func (n *Node) Interface() interface{} {
+	switch n.Kind {
+	case ArrayNode:
+		items := make([]interface{}, 0, len(n.Items))
+
+		for _, item := range n.Items {
+			items = append(items, item.Interface())
+		}
+
+		return items
+	case ObjectNode:
+		entries := make(map[string]interface{}, len(n.Entries))
+
+		for _, e := range n.Entries {
+			value := e.Value.Interface()
+
+			if current, ok := entries[e.Key]; ok {
+				value = mergeValues(current, value)
+			}
+
+			entries[e.Key] = value
+		}
+
+		return entries
+	}
+
+	return n.Value
+}
+
+// mergeValues returns the value of a key set to current and then to next,
+// objects are merged and arrays appended
+func mergeValues(current interface{}, next interface{}) interface{} {
+	switch c := current.(type) {
+	case map[string]interface{}:
+		if n, ok := next.(map[string]interface{}); ok {
+			for key, value := range n {
+				if old, ok := c[key]; ok {
+					value = mergeValues(old, value)
+				}
+
+				c[key] = value
+			}
+
+			return c
+		}
+	case *object:
+		if n, ok := next.(*object); ok {
+			for _, key := range n.keys {
+				value := n.values[key]
+
+				if old, ok := c.values[key]; ok {
+					value = mergeValues(old, value)
+				}
+
+				c.set(key, value)
+			}
+
+			return c
+		}
+	case []interface{}:
+		if n, ok := next.([]interface{}); ok {
+			return append(c, n...)
+		}
+	}
+
+	return next
+}
+
+// keyValues returns the values of the entries of nodes with key, the way
+// Unmarshal decodes a repeated key: the last value and the values of the
+// same kind right before it, inner structs to merge or arrays to append
+func keyValues(nodes []*Node, key string) []*Node {
+	var found []*Node
+
+	for _, n := range nodes {
+		for _, e := range n.Entries {
+			if e.Key == key {
+				found = append(found, e.Value)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	last := found[len(found)-1]
+	start := len(found) - 1
+
+	for last.Kind != ScalarNode && start > 0 && found[start-1].Kind == last.Kind {
+		start--
+	}
+
+	return found[start:]
+}
+
+// merged returns a node with the entries or elements of nodes, returned
+// by keyValues, the node itself when there is only one
+func merged(nodes []*Node) *Node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	m := &Node{Kind: nodes[0].Kind, Pos: nodes[0].Pos}
+
+	for _, n := range nodes {
+		m.Entries = append(m.Entries, n.Entries...)
+		m.Items = append(m.Items, n.Items...)
+	}
+
+	return m
+}
+
+// field returns the value of key as decoded by Unmarshal, see keyValues,
+// nil when there is no entry with key
+func (n *Node) field(key string) *Node {
+	found := keyValues([]*Node{n}, key)
+	if len(found) == 0 {
+		return nil
+	}
+
+	return merged(found)
+}
+
+// entry returns the last entry with key, nil when there is none
+func (n *Node) entry(key string) *Entry {
+	for i := len(n.Entries) - 1; i >= 0; i-- {
+		if n.Entries[i].Key == key {
+			return n.Entries[i]
+		}
+	}
+
+	return nil
+}
+
+// splitIndexes splits a path segment like tags[2] into its key and indexes
+func splitIndexes(segment string) (string, []int, bool) {
+	start := strings.Index(segment, "[")
+	if start < 0 {
+		return segment, nil, len(segment) > 0
+	}
+
+	key := segment[0:start]
+	rest := segment[start:]
+
+	var indexes []int
+
+	for len(rest) > 0 {
+		end := strings.Index(rest, "]")
+		if rest[0] != '[' || end < 0 {
+			return "", nil, false
+		}
+
+		index, err := strconv.Atoi(rest[1:end])
+		if err != nil || index < 0 {
+			return "", nil, false
+		}
+
+		indexes = append(indexes, index)
+		rest = rest[end+1:]
+	}
+
+	return key, indexes, len(key) > 0
+}
+
+func lookup(data []byte, path string) (*Node, error) {
+	root, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return root.Lookup(path)
+}
+
+func lookupScalar(data []byte, path string) (*Node, error) {
+	n, err := lookup(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Kind != ScalarNode {
+		return nil, errors.New(fmt.Sprintf("could not convert %s on line %d, expected a value, got %s", path, n.Pos.Line, n.Kind))
+	}
+
+	return n, nil
+}
+
+// Get returns the value at path, see Node.Lookup for the path syntax
+// and Node.Interface for the returned types
+func Get(data []byte, path string) (interface{}, error) {
+	n, err := lookup(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.Interface(), nil
+}
+
+// Has reports whether path exists in data
+func Has(data []byte, path string) bool {
+	_, err := lookup(data, path)
+	return err == nil
+}
+
+// GetString returns the value at path as a string
+func GetString(data []byte, path string) (string, error) {
+	n, err := lookupScalar(data, path)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Value, nil
+}
+
+// GetInt returns the value at path as an int
+func GetInt(data []byte, path string) (int, error) {
+	n, err := lookupScalar(data, path)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(n.Value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("could not convert %s on line %d, %q is not an int", path, n.Pos.Line, n.Value))
+	}
+
+	return i, nil
+}
+
+// GetBool returns the value at path as a bool, accepting the same
+// values as Unmarshal (true, yes, y, t and 1, case insensitive)
+// and their negations
+func GetBool(data []byte, path string) (bool, error) {
+	n, err := lookupScalar(data, path)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(n.Value) {
+	case "t", "true", "y", "yes", "1":
+		return true, nil
+	case "f", "false", "n", "no", "0":
+		return false, nil
+	}
+
+	return false, errors.New(fmt.Sprintf("could not convert %s on line %d, %q is not a bool", path, n.Pos.Line, n.Value))
+}
+
+// GetStringSlice returns the array at path as a string slice
+func GetStringSlice(data []byte, path string) ([]string, error) {
+	n, err := lookup(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Kind != ArrayNode {
+		return nil, errors.New(fmt.Sprintf("could not convert %s on line %d, expected an array, got %s", path, n.Pos.Line, n.Kind))
+	}
+
+	values := make([]string, 0, len(n.Items))
+
+	for i, item := range n.Items {
+		if item.Kind != ScalarNode {
+			return nil, errors.New(fmt.Sprintf("could not convert %s[%d] on line %d, expected a value, got %s", path, i, item.Pos.Line, item.Kind))
+		}
+
+		values = append(values, item.Value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,189 @@
+package cfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	data := []byte(completeExample)
+
+	t.Run("values", func(t *testing.T) {
+		port, err := GetInt(data, "voice.externalPort")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if port != 7798 {
+			t.Fatalf("wrong value, expected 7798, got %d", port)
+		}
+
+		name, err := GetString(data, "name")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name != "TestServer" {
+			t.Fatalf("wrong value, expected TestServer, got %q", name)
+		}
+
+		useCdn, err := GetBool(data, "useCdn")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !useCdn {
+			t.Fatal("wrong value, expected true, got false")
+		}
+
+		tags, err := GetStringSlice(data, "tags")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tags, []string{"customTag1", "customTag2", "customTag3", "customTag4"}) {
+			t.Fatalf("wrong value, got %v", tags)
+		}
+
+		tag, err := GetString(data, "tags[2]")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tag != "customTag3" {
+			t.Fatalf("wrong value, expected customTag3, got %q", tag)
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		v, err := Get([]byte("a: [x, [y]]\nb: { c: d }"), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, []interface{}{"x", []interface{}{"y"}}) {
+			t.Fatalf("wrong value, got %v", v)
+		}
+
+		v, err = Get([]byte("a: [x, [y]]\nb: { c: d }"), "b")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(v, map[string]interface{}{"c": "d"}) {
+			t.Fatalf("wrong value, got %v", v)
+		}
+	})
+
+	t.Run("has", func(t *testing.T) {
+		if !Has(data, "voice.bitrate") {
+			t.Fatal("expected voice.bitrate to exist")
+		}
+
+		if Has(data, "voice.externalSecret") {
+			t.Fatal("expected commented voice.externalSecret to not exist")
+		}
+	})
+
+	t.Run("path errors", func(t *testing.T) {
+		cases := []struct {
+			path    string
+			segment string
+		}{
+			{"voice.missing", "voice.missing"},
+			{"missing.value", "missing"},
+			{"name.value", "name.value"},
+			{"tags[4]", "tags[4]"},
+			{"tags[1][0]", "tags[1][0]"},
+			{"name[0]", "name[0]"},
+			{"tags[x]", "tags[x]"},
+			{"voice..bitrate", "voice."},
+		}
+
+		for _, c := range cases {
+			_, err := Get(data, c.path)
+
+			pathErr, ok := err.(*PathError)
+			if !ok {
+				t.Fatalf("expected *PathError for %q, got %v", c.path, err)
+			}
+
+			if pathErr.Segment != c.segment {
+				t.Fatalf("wrong segment for %q, expected %q, got %q", c.path, c.segment, pathErr.Segment)
+			}
+		}
+	})
+
+	t.Run("type errors", func(t *testing.T) {
+		if _, err := GetInt(data, "name"); err == nil {
+			t.Fatal("expected error converting name to int")
+		}
+
+		if _, err := GetBool(data, "name"); err == nil {
+			t.Fatal("expected error converting name to bool")
+		}
+
+		if _, err := GetString(data, "voice"); err == nil {
+			t.Fatal("expected error converting voice to string")
+		}
+
+		if _, err := GetStringSlice(data, "name"); err == nil {
+			t.Fatal("expected error converting name to slice")
+		}
+	})
+
+	t.Run("last value wins", func(t *testing.T) {
+		v, err := GetString([]byte("a: 1\na: 2"), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != "2" {
+			t.Fatalf("wrong value, expected 2, got %q", v)
+		}
+	})
+
+	t.Run("repeated keys like Unmarshal", func(t *testing.T) {
+		data := []byte("voice: { bitrate: 1, tags: [a] }\nvoice: { port: 2, tags: [b] }")
+
+		var v struct {
+			Voice struct {
+				BitRate int      `cfg:"bitrate"`
+				Port    int      `cfg:"port"`
+				Tags    []string `cfg:"tags"`
+			} `cfg:"voice"`
+		}
+
+		err := Unmarshal(data, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bitrate, err := GetInt(data, "voice.bitrate")
+		if err != nil || bitrate != v.Voice.BitRate {
+			t.Fatalf("expected %d, got %d %v", v.Voice.BitRate, bitrate, err)
+		}
+
+		tags, err := GetStringSlice(data, "voice.tags")
+		if err != nil || !reflect.DeepEqual(tags, v.Voice.Tags) {
+			t.Fatalf("expected %v, got %v %v", v.Voice.Tags, tags, err)
+		}
+
+		tag, err := GetString(data, "voice.tags[1]")
+		if err != nil || tag != "b" {
+			t.Fatalf("expected b, got %q %v", tag, err)
+		}
+
+		voice, err := Get(data, "voice")
+		expected := map[string]interface{}{"bitrate": "1", "port": "2", "tags": []interface{}{"a", "b"}}
+
+		if err != nil || !reflect.DeepEqual(voice, expected) {
+			t.Fatalf("expected %v, got %v %v", expected, voice, err)
+		}
+
+		if _, err := GetString(data, "voice.tags[2]"); err == nil || !strings.Contains(err.Error(), "voice.tags has 2 elements") {
+			t.Fatalf("expected an index error, got %v", err)
+		}
+	})
+}
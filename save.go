@@ -0,0 +1,208 @@
+package cfg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backupTimeFormat sorts lexically in chronological order
+const backupTimeFormat = "20060102-150405.000000000"
+
+// now returns the time used on backup names
+var now = time.Now
+
+// SaveOptions configures SaveFile
+type SaveOptions struct {
+	// Mode is used when the file does not exist yet, 0644 when zero.
+	// The mode of an existing file is preserved
+	Mode os.FileMode
+	// Backups is the number of timestamped backups of the previous
+	// contents to keep, like server.cfg.20200102-150405.000000000.bak,
+	// none when zero
+	Backups int
+}
+
+// SaveFile encodes v with Marshal and atomically replaces the file at path.
+// The data is written to a temporary file in the same directory, synced to
+// disk and renamed into place, so a crash never leaves a truncated file.
+// opts may be nil
+func SaveFile(path string, v interface{}, opts *SaveOptions) error {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		v = rv.Elem().Interface()
+	}
+
+	data, err := Marshal(v)
+	if err != nil {
+		return &FileError{Chain: []string{path}, Err: err}
+	}
+
+	return WriteFileAtomic(path, append(data, '\n'), opts)
+}
+
+// WriteFileAtomic replaces the file at path with data the way SaveFile
+// does, for files written without Marshal, like formatted or edited
+// documents. opts may be nil
+func WriteFileAtomic(path string, data []byte, opts *SaveOptions) error {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+
+	err := writeFileAtomic(path, data, opts)
+	if err != nil {
+		return &FileError{Chain: []string{path}, Err: err}
+	}
+
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, opts *SaveOptions) error {
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+
+	var previous []byte
+
+	info, err := os.Stat(path)
+	if err == nil {
+		mode = info.Mode().Perm()
+
+		if opts.Backups > 0 {
+			previous, err = ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrap(err, "could not read the current file")
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir, name := filepath.Split(path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary file")
+	}
+
+	// removing fails once the file is renamed into place
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "could not write temporary file")
+	}
+
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return errors.Wrap(err, "could not set file mode")
+	}
+
+	if previous != nil {
+		err = backup(path, previous, mode, opts.Backups)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return errors.Wrap(err, "could not replace file")
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// backup writes data to a new timestamped backup of path,
+// removing the oldest ones so only keep backups remain
+func backup(path string, data []byte, mode os.FileMode, keep int) error {
+	name := fmt.Sprintf("%s.%s.bak", path, now().Format(backupTimeFormat))
+
+	err := ioutil.WriteFile(name, data, mode)
+	if err != nil {
+		return errors.Wrap(err, "could not write backup")
+	}
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return errors.Wrap(err, "could not list backups")
+	}
+
+	for len(backups) > keep {
+		err := os.Remove(backups[0])
+		if err != nil {
+			return errors.Wrap(err, "could not remove old backup")
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// listBackups returns the backups of path, oldest first
+func listBackups(path string) ([]string, error) {
+	dir, name := filepath.Split(path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+
+	for _, f := range files {
+		suffix := strings.TrimPrefix(f.Name(), name+".")
+
+		if f.IsDir() || suffix == f.Name() || !strings.HasSuffix(suffix, ".bak") {
+			continue
+		}
+
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(suffix, ".bak")); err != nil {
+			continue
+		}
+
+		backups = append(backups, filepath.Join(dir, f.Name()))
+	}
+
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// syncDir flushes the rename to disk, not every platform supports
+// syncing directories so errors are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	_ = d.Sync()
+	_ = d.Close()
+}
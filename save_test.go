@@ -0,0 +1,181 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	defer func() {
+		now = time.Now
+	}()
+
+	clock := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	type config struct {
+		Name string `cfg:"name"`
+		Port int    `cfg:"port"`
+	}
+
+	path := filepath.Join(dir, "server.cfg")
+
+	t.Run("new file", func(t *testing.T) {
+		err := SaveFile(path, &config{Name: "test", Port: 1}, &SaveOptions{Mode: 0600, Backups: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var v config
+
+		err = LoadFile(path, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Name != "test" || v.Port != 1 {
+			t.Fatalf("wrong value saved, got %+v", v)
+		}
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm() != 0600 {
+				t.Fatalf("wrong mode, expected 0600, got %v", info.Mode().Perm())
+			}
+		}
+	})
+
+	t.Run("backups", func(t *testing.T) {
+		for port := 2; port <= 5; port++ {
+			err := SaveFile(path, config{Name: "test", Port: port}, &SaveOptions{Backups: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		backups, err := listBackups(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(backups) != 2 {
+			t.Fatalf("wrong number of backups, expected 2, got %v", backups)
+		}
+
+		// the newest backup has the contents before the last save
+		var v config
+
+		err = LoadFile(backups[1], &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Port != 4 {
+			t.Fatalf("wrong backup contents, expected port 4, got %d", v.Port)
+		}
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm() != 0600 {
+				t.Fatalf("expected the mode to be preserved, got %v", info.Mode().Perm())
+			}
+		}
+	})
+
+	t.Run("failed encoding keeps the file", func(t *testing.T) {
+		err := SaveFile(path, struct{ Value map[string]string }{}, nil)
+		if err == nil {
+			t.Fatal("expected error encoding a map")
+		}
+
+		var v config
+
+		err = LoadFile(path, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Port != 5 {
+			t.Fatalf("expected the file to be kept, got port %d", v.Port)
+		}
+	})
+
+	t.Run("no temporary files left", func(t *testing.T) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 3 {
+			var names []string
+
+			for _, f := range files {
+				names = append(names, f.Name())
+			}
+
+			t.Fatalf("expected the file and two backups, got %v", names)
+		}
+	})
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.cfg")
+
+	err = WriteFileAtomic(path, []byte("port: 7788\n"), &SaveOptions{Mode: 0600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteFileAtomic(path, []byte("port: 7789\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil || string(data) != "port: 7789\n" {
+		t.Fatalf("expected the new data, got %q %v", data, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the mode to be kept, got %o", info.Mode().Perm())
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "server.cfg"), nil, nil); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
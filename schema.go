@@ -0,0 +1,282 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const descTagName = "desc"
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// object is a JSON object that keeps the order of its keys
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]interface{})}
+}
+
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.values[key] = value
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// JSONSchema returns a draft-07 JSON Schema describing the CFG accepted by
+// the struct v, or a pointer to it, following the same tags as Unmarshal.
+// Non-zero field values become defaults, the desc tag becomes the
+// description and the validate tags become bounds, enums, patterns,
+// formats and required fields. Fields of unsupported types are left out
+func JSONSchema(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema target should be a struct, got %s", reflect.TypeOf(v))
+	}
+
+	plan := planOf(rv.Type())
+
+	if plan.rulesErr != nil {
+		return nil, plan.rulesErr
+	}
+
+	schema := newObject()
+	schema.set("$schema", schemaDraft)
+
+	structSchema(schema, rv)
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// structSchema adds the properties of the struct rv to schema
+func structSchema(schema *object, rv reflect.Value) {
+	t := rv.Type()
+	plan := planOf(t)
+	properties := newObject()
+
+	var required []string
+	var conditions []interface{}
+
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		if fp.tag == "-" || !fp.exported || (fp.set == nil && !fp.isInner) {
+			continue
+		}
+
+		property := newObject()
+		value := rv.Field(fp.index)
+
+		if desc := t.Field(fp.index).Tag.Get(descTagName); len(desc) > 0 {
+			property.set("description", desc)
+		}
+
+		switch {
+		case fp.isInner:
+			structSchema(property, value)
+		case fp.isArray:
+			items := newObject()
+			items.set("type", schemaType(value.Type().Elem()))
+
+			property.set("type", "array")
+			property.set("items", items)
+
+			for _, r := range fp.rules {
+				if r.checkField == nil {
+					ruleSchema(items, r, value.Type().Elem())
+				}
+			}
+
+			if value.Len() > 0 {
+				property.set("default", value.Interface())
+			}
+		default:
+			property.set("type", schemaType(value.Type()))
+
+			if value.Kind() >= reflect.Uint && value.Kind() <= reflect.Uint64 {
+				property.set("minimum", 0)
+			}
+
+			for _, r := range fp.rules {
+				ruleSchema(property, r, value.Type())
+			}
+
+			if !value.IsZero() {
+				property.set("default", value.Interface())
+			}
+		}
+
+		for _, r := range fp.rules {
+			switch r.name {
+			case "nonempty":
+				required = append(required, fp.tag)
+
+				if fp.isArray {
+					property.set("minItems", 1)
+				}
+			case "required_if":
+				conditions = append(conditions, requiredIfSchema(plan, rv, fp.tag, r.param))
+			}
+		}
+
+		properties.set(fp.tag, property)
+	}
+
+	schema.set("type", "object")
+	schema.set("properties", properties)
+
+	if len(required) > 0 {
+		schema.set("required", required)
+	}
+
+	if len(conditions) > 0 {
+		schema.set("allOf", conditions)
+	}
+}
+
+// ruleSchema adds the keywords of the validate rule r to schema, for values of type t
+func ruleSchema(schema *object, r rule, t reflect.Type) {
+	param := strings.TrimSpace(r.param)
+	isString := t.Kind() == reflect.String
+
+	switch r.name {
+	case "nonempty":
+		if isString {
+			schema.set("minLength", 1)
+		}
+	case "min", "max":
+		bound, _ := strconv.ParseFloat(param, 64)
+		keyword := "minimum"
+
+		if isString {
+			keyword = "minLength"
+		}
+
+		if r.name == "max" {
+			keyword = strings.Replace(keyword, "min", "max", 1)
+		}
+
+		schema.set(keyword, bound)
+	case "len":
+		length, _ := strconv.Atoi(param)
+		schema.set("minLength", length)
+		schema.set("maxLength", length)
+	case "oneof":
+		var enum []interface{}
+
+		for _, option := range strings.Fields(param) {
+			enum = append(enum, schemaValue(option, t))
+		}
+
+		schema.set("enum", enum)
+	case "regex":
+		schema.set("pattern", r.param)
+	case "url":
+		schema.set("format", "uri")
+	case "hostname":
+		schema.set("format", "hostname")
+	case "ip":
+		ipv4 := newObject()
+		ipv4.set("format", "ipv4")
+
+		ipv6 := newObject()
+		ipv6.set("format", "ipv6")
+
+		schema.set("anyOf", []interface{}{ipv4, ipv6})
+	case "port":
+		if isString {
+			schema.set("pattern", "^[0-9]+$")
+			return
+		}
+
+		schema.set("minimum", 1)
+		schema.set("maximum", 65535)
+	}
+}
+
+// requiredIfSchema returns the condition of a required_if rule, like
+// required_if=useCdn true, on the field key of the struct rv
+func requiredIfSchema(plan *structPlan, rv reflect.Value, key string, param string) *object {
+	parts := strings.Fields(param)
+	value := interface{}(parts[1])
+
+	for _, i := range plan.byTag[parts[0]] {
+		value = schemaValue(parts[1], rv.Type().Field(plan.fields[i].index).Type)
+	}
+
+	condition := newObject()
+	condition.set(parts[0], map[string]interface{}{"const": value})
+
+	ifSchema := newObject()
+	ifSchema.set("properties", condition)
+	ifSchema.set("required", []string{parts[0]})
+
+	schema := newObject()
+	schema.set("if", ifSchema)
+	schema.set("then", map[string]interface{}{"required": []string{key}})
+	return schema
+}
+
+func schemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+
+	return "integer"
+}
+
+// schemaValue converts s to the JSON type of t
+func schemaValue(s string, t reflect.Type) interface{} {
+	switch schemaType(t) {
+	case "boolean":
+		return boolValue(s)
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+
+	return s
+}
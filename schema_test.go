@@ -0,0 +1,159 @@
+package cfg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	type target struct {
+		Name     string            `cfg:"name" desc:"Server name" validate:"nonempty,max=64"`
+		Port     int               `cfg:"port" validate:"port"`
+		Players  uint              `cfg:"players" validate:"max=4096"`
+		Ratio    float32           `cfg:"ratio"`
+		Mode     string            `cfg:"mode" validate:"oneof=race freeroam"`
+		Announce bool              `cfg:"announce"`
+		Token    string            `cfg:"token" validate:"required_if=announce true,len=32"`
+		CDNUrl   string            `cfg:"cdnUrl" validate:"url"`
+		Tags     []string          `cfg:"tags" validate:"nonempty,regex=^[a-z]+$"`
+		Ignored  string            `cfg:"-"`
+		Other    map[string]string `cfg:"other"`
+		Levels   []int             `cfg:"levels" validate:"oneof=1 2"`
+		Voice    struct {
+			Host string `cfg:"externalHost" validate:"ip"`
+		} `cfg:"voice" desc:"Voice chat"`
+		unexported string
+	}
+
+	data, err := JSONSchema(&target{Port: 7788, Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "name": {
+      "description": "Server name",
+      "type": "string",
+      "minLength": 1,
+      "maxLength": 64
+    },
+    "port": {
+      "type": "integer",
+      "minimum": 1,
+      "maximum": 65535,
+      "default": 7788
+    },
+    "players": {
+      "type": "integer",
+      "minimum": 0,
+      "maximum": 4096
+    },
+    "ratio": {
+      "type": "number"
+    },
+    "mode": {
+      "type": "string",
+      "enum": [
+        "race",
+        "freeroam"
+      ]
+    },
+    "announce": {
+      "type": "boolean"
+    },
+    "token": {
+      "type": "string",
+      "minLength": 32,
+      "maxLength": 32
+    },
+    "cdnUrl": {
+      "type": "string",
+      "format": "uri"
+    },
+    "tags": {
+      "type": "array",
+      "items": {
+        "type": "string",
+        "pattern": "^[a-z]+$"
+      },
+      "default": [
+        "a"
+      ],
+      "minItems": 1
+    },
+    "levels": {
+      "type": "array",
+      "items": {
+        "type": "integer",
+        "enum": [
+          1,
+          2
+        ]
+      }
+    },
+    "voice": {
+      "description": "Voice chat",
+      "type": "object",
+      "properties": {
+        "externalHost": {
+          "type": "string",
+          "anyOf": [
+            {
+              "format": "ipv4"
+            },
+            {
+              "format": "ipv6"
+            }
+          ]
+        }
+      }
+    }
+  },
+  "required": [
+    "name",
+    "tags"
+  ],
+  "allOf": [
+    {
+      "if": {
+        "properties": {
+          "announce": {
+            "const": true
+          }
+        },
+        "required": [
+          "announce"
+        ]
+      },
+      "then": {
+        "required": [
+          "token"
+        ]
+      }
+    }
+  ]
+}`
+
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+
+	if !json.Valid(data) {
+		t.Fatal("expected valid JSON")
+	}
+
+	_, err = JSONSchema(1)
+	if err == nil {
+		t.Fatal("expected error for non-struct")
+	}
+
+	_, err = JSONSchema(struct {
+		A int `validate:"url"`
+	}{})
+	if err == nil {
+		t.Fatal("expected error for invalid validate tag")
+	}
+}
@@ -0,0 +1,66 @@
+package cfg
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ReloadResult is the result of a reload triggered by a signal
+type ReloadResult struct {
+	// Err is the error of the reload, nil on success. Decoding errors
+	// are FileErrors wrapping a SyntaxError with the position
+	Err error
+	// Changes are the fields that changed, empty when none did
+	Changes []Change
+}
+
+// ReloadOnSignal reloads w when the process receives SIGHUP, until ctx is
+// done. Signals received within debounce of each other cause a single
+// reload, after the last one. Every reload sends a result on the returned
+// channel, which must be received from, and is closed when ctx is done
+func ReloadOnSignal(ctx context.Context, w *Watcher, debounce time.Duration) <-chan ReloadResult {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	results := make(chan ReloadResult)
+
+	go func() {
+		defer close(results)
+		defer signal.Stop(signals)
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				timer.Stop()
+
+				// drain a timer that fired while the signal was received
+				select {
+				case <-timer.C:
+				default:
+				}
+
+				timer.Reset(debounce)
+			case <-timer.C:
+				changes, err := w.reloadChanges()
+
+				select {
+				case results <- ReloadResult{Err: err, Changes: changes}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return results
+}
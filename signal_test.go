@@ -0,0 +1,89 @@
+//go:build !windows
+// +build !windows
+
+package cfg
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	fs := MapFS{"server.cfg": []byte("port: 80")}
+
+	w, err := NewWatcher("server.cfg", WatcherOptions{FS: fs, New: newWatcherConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := ReloadOnSignal(ctx, w, 50*time.Millisecond)
+
+	// the signal handler is installed asynchronously by the runtime
+	time.Sleep(10 * time.Millisecond)
+
+	receive := func() ReloadResult {
+		select {
+		case result := <-results:
+			return result
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a reload result")
+		}
+
+		return ReloadResult{}
+	}
+
+	// MapFS is not safe for concurrent use, the changes go through the reload lock
+	w.mu.Lock()
+	fs["server.cfg"] = []byte("port: 81")
+	w.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		err = syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := receive()
+
+	if result.Err != nil || len(result.Changes) != 1 || result.Changes[0].Path != "port" {
+		t.Fatalf("expected port to change, got %+v", result)
+	}
+
+	select {
+	case result := <-results:
+		t.Fatalf("expected a single reload for the debounced signals, got %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	w.mu.Lock()
+	fs["server.cfg"] = []byte("port: 82\nname: 'broken")
+	w.mu.Unlock()
+
+	err = syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result = receive()
+
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "server.cfg: could not decode line 2") {
+		t.Fatalf("expected a positioned error, got %v", result.Err)
+	}
+
+	if w.Current().(*watcherConfig).Port != 81 {
+		t.Fatalf("expected the last good config to be kept")
+	}
+
+	cancel()
+
+	if _, ok := <-results; ok {
+		t.Fatal("expected the results to be closed")
+	}
+}
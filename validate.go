@@ -0,0 +1,551 @@
+package cfg
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const validateTagName = "validate"
+
+// ValidationError is a field that failed a rule of its validate tag
+type ValidationError struct {
+	// Path is the key path, like voice.externalPort or tags[2],
+	// empty for the decoded struct
+	Path string
+	// Rule is the failed rule, like max, or Validate for errors
+	// returned by the Validate method
+	Rule string
+	Msg  string
+	// Pos is the position of the key, zero when unknown
+	Pos Position
+	// Source is the name of the input with the key, empty when unknown
+	Source string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+
+	// errors of the Validate method of the decoded struct
+	if len(path) == 0 {
+		path = "config"
+	}
+
+	msg := fmt.Sprintf("invalid %s, %s", path, e.Msg)
+
+	if e.Pos.Line > 0 {
+		msg = fmt.Sprintf("invalid %s on line %d, %s", path, e.Pos.Line, e.Msg)
+	}
+
+	if len(e.Source) > 0 {
+		msg = e.Source + ": " + msg
+	}
+
+	return msg
+}
+
+// ValidationErrors are the fields that failed validation, in field order
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// validator is implemented by structs with a Validate method, called after
+// their fields are validated
+type validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*validator)(nil)).Elem()
+
+// rule is a compiled rule of a validate tag, checks return why the
+// value failed the rule, empty when it passed
+type rule struct {
+	name  string
+	param string
+	// check validates a value, each element for slices
+	check func(v reflect.Value) string
+	// checkField validates the whole field, with the struct holding it
+	checkField func(v reflect.Value, parent reflect.Value) string
+}
+
+// parseRules compiles a validate tag like "min=1,max=65535" for values of
+// type t, the element type for slices, of a field of the struct type parent.
+// The regex rule takes the rest of the tag, so the expression can contain commas
+func parseRules(tag string, t reflect.Type, slice bool, parent reflect.Type) ([]rule, error) {
+	var rules []rule
+
+	for len(tag) > 0 {
+		part := tag
+		tag = ""
+
+		if !strings.HasPrefix(strings.TrimSpace(part), "regex=") {
+			if sep := strings.Index(part, ","); sep >= 0 {
+				part, tag = part[0:sep], part[sep+1:]
+			}
+		}
+
+		name, param := strings.TrimSpace(part), ""
+
+		if sep := strings.Index(part, "="); sep >= 0 {
+			name, param = strings.TrimSpace(part[0:sep]), part[sep+1:]
+		}
+
+		if len(name) == 0 {
+			continue
+		}
+
+		r, err := compileRule(name, param, t, slice, parent)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s, %s", name, err)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func compileRule(name string, param string, t reflect.Type, slice bool, parent reflect.Type) (rule, error) {
+	kind := t.Kind()
+	isString := kind == reflect.String
+	isNumber := scalarSetter(t) != nil && !isString && kind != reflect.Bool
+
+	if scalarSetter(t) == nil {
+		return rule{}, fmt.Errorf("%s is not supported", t)
+	}
+
+	r := rule{name: name, param: param}
+
+	switch name {
+	case "nonempty":
+		if slice {
+			// checked on the slice, not on its elements
+			r.checkField = func(v reflect.Value, _ reflect.Value) string {
+				if v.Len() == 0 {
+					return "must not be empty"
+				}
+
+				return ""
+			}
+
+			return r, nil
+		}
+
+		if !isString {
+			return r, fmt.Errorf("only applies to strings and slices")
+		}
+
+		r.check = func(v reflect.Value) string {
+			if len(strings.TrimSpace(v.String())) == 0 {
+				return "must not be empty"
+			}
+
+			return ""
+		}
+	case "required_if":
+		return requiredIf(r, param, parent)
+	case "min", "max":
+		bound, err := strconv.ParseFloat(strings.TrimSpace(param), 64)
+		if err != nil {
+			return r, fmt.Errorf("%q is not a number", param)
+		}
+
+		if !isString && !isNumber {
+			return r, fmt.Errorf("only applies to numbers and strings")
+		}
+
+		r.check = func(v reflect.Value) string {
+			n, unit := numberOf(v), ""
+
+			if isString {
+				n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+			}
+
+			if name == "min" && n < bound {
+				return fmt.Sprintf("must be at least %s%s, got %v", strings.TrimSpace(param), unit, n)
+			}
+
+			if name == "max" && n > bound {
+				return fmt.Sprintf("must be at most %s%s, got %v", strings.TrimSpace(param), unit, n)
+			}
+
+			return ""
+		}
+	case "len":
+		length, err := strconv.Atoi(strings.TrimSpace(param))
+		if err != nil {
+			return r, fmt.Errorf("%q is not an int", param)
+		}
+
+		if !isString {
+			return r, fmt.Errorf("only applies to strings")
+		}
+
+		r.check = func(v reflect.Value) string {
+			if n := utf8.RuneCountInString(v.String()); n != length {
+				return fmt.Sprintf("must have %d characters, got %d", length, n)
+			}
+
+			return ""
+		}
+	case "oneof":
+		options := strings.Fields(param)
+
+		if len(options) == 0 {
+			return r, fmt.Errorf("expected a space separated list of values")
+		}
+
+		r.check = func(v reflect.Value) string {
+			value := fmt.Sprint(v.Interface())
+
+			for _, option := range options {
+				if value == option {
+					return ""
+				}
+			}
+
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(options, ", "))
+		}
+	case "regex":
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return r, err
+		}
+
+		if !isString {
+			return r, fmt.Errorf("only applies to strings")
+		}
+
+		r.check = stringCheck(func(s string) bool { return re.MatchString(s) }, "does not match "+param)
+	case "url":
+		if !isString {
+			return r, fmt.Errorf("only applies to strings")
+		}
+
+		r.check = stringCheck(isURL, "is not a valid URL")
+	case "hostname":
+		if !isString {
+			return r, fmt.Errorf("only applies to strings")
+		}
+
+		r.check = stringCheck(isHostname, "is not a valid hostname")
+	case "ip":
+		if !isString {
+			return r, fmt.Errorf("only applies to strings")
+		}
+
+		r.check = stringCheck(func(s string) bool { return net.ParseIP(s) != nil }, "is not a valid IP address")
+	case "port":
+		if !isString && !isNumber {
+			return r, fmt.Errorf("only applies to numbers and strings")
+		}
+
+		r.check = func(v reflect.Value) string {
+			n := numberOf(v)
+
+			if isString {
+				i, err := strconv.Atoi(v.String())
+				if err != nil {
+					return fmt.Sprintf("%q is not a valid port", v.String())
+				}
+
+				n = float64(i)
+			}
+
+			if n < 1 || n > 65535 || n != float64(int(n)) {
+				return fmt.Sprintf("%v is not a valid port", n)
+			}
+
+			return ""
+		}
+	default:
+		return r, fmt.Errorf("unknown rule")
+	}
+
+	return r, nil
+}
+
+// requiredIf compiles a rule like required_if=useCdn true, requiring the
+// field when the field of parent with the key useCdn has the value true
+func requiredIf(r rule, param string, parent reflect.Type) (rule, error) {
+	parts := strings.Fields(param)
+	if len(parts) != 2 {
+		return r, fmt.Errorf("expected a key and a value, like useCdn true")
+	}
+
+	key, want := parts[0], parts[1]
+	index := -1
+
+	for i := 0; i < parent.NumField(); i++ {
+		tag, _ := parseTag(parent.Field(i).Tag.Get(tagName))
+
+		if len(tag) == 0 {
+			tag = parent.Field(i).Name
+		}
+
+		if tag == key && parent.Field(i).PkgPath == "" {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return r, fmt.Errorf("field with key %s not found", key)
+	}
+
+	if parent.Field(index).Type.Kind() == reflect.Bool {
+		want = strconv.FormatBool(boolValue(want))
+	}
+
+	r.checkField = func(v reflect.Value, parent reflect.Value) string {
+		if fmt.Sprint(parent.Field(index).Interface()) != want || !isEmpty(v) {
+			return ""
+		}
+
+		return fmt.Sprintf("must be set when %s is %s", key, parts[1])
+	}
+
+	return r, nil
+}
+
+// isEmpty reports whether v is the zero value, a blank string or an empty slice
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return len(strings.TrimSpace(v.String())) == 0
+	case reflect.Slice:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+// stringCheck returns a check failing with msg when ok returns false,
+// empty strings are accepted, use nonempty to require a value
+func stringCheck(ok func(s string) bool, msg string) func(v reflect.Value) string {
+	return func(v reflect.Value) string {
+		s := v.String()
+
+		if len(s) == 0 || ok(s) {
+			return ""
+		}
+
+		return fmt.Sprintf("%q %s", s, msg)
+	}
+}
+
+func numberOf(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+
+	return 0
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && len(u.Scheme) > 0 && len(u.Host) > 0
+}
+
+// isHostname reports whether s is a hostname as described by RFC 1123
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Validate checks the struct pointed by v against the validate tags of
+// its fields, like `validate:"min=1,max=65535"`, returning ValidationErrors
+// with every failure. Rules on slices apply to each element, except
+// nonempty which requires the slice to have elements, and inner structs
+// are validated recursively. When md is not nil the errors have the
+// position and source of the keys.
+//
+// After its fields, the Validate() error method of the struct and its
+// inner structs is called, when they have one, and the error is collected
+// with the position of the inner struct key. Validate can return a
+// ValidationError, or ValidationErrors, with a Path relative to the struct
+// to report the position of its fields.
+//
+// The rules are min and max, bounds for numbers and the length of strings,
+// len, the length of strings, oneof, a space separated list of values,
+// regex, a regular expression that takes the rest of the tag, url,
+// hostname, ip, port and nonempty. The url, hostname, ip and regex rules
+// accept empty strings, combine them with nonempty to require a value.
+//
+// Unmarshal, the Decoder and the Load functions validate after decoding
+func Validate(v interface{}, md *Metadata) error {
+	rv, err := decodeTarget(v)
+	if err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+
+	err = validateStruct(rv, "", md, &errs)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, md *Metadata, errs *ValidationErrors) error {
+	plan := planOf(rv.Type())
+
+	if plan.rulesErr != nil {
+		return plan.rulesErr
+	}
+
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		if fp.tag == "-" || !fp.exported {
+			continue
+		}
+
+		path := joinPath(prefix, fp.tag)
+		value := rv.Field(fp.index)
+
+		if fp.isInner {
+			err := validateStruct(value, path, md, errs)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for _, r := range fp.rules {
+			if r.checkField != nil {
+				if msg := r.checkField(value, rv); len(msg) > 0 {
+					*errs = append(*errs, validationError(md, path, path, r.name, msg))
+				}
+
+				continue
+			}
+
+			if fp.isArray {
+				for j := 0; j < value.Len(); j++ {
+					if msg := r.check(value.Index(j)); len(msg) > 0 {
+						*errs = append(*errs, validationError(md, path, fmt.Sprintf("%s[%d]", path, j), r.name, msg))
+					}
+				}
+
+				continue
+			}
+
+			if msg := r.check(value); len(msg) > 0 {
+				*errs = append(*errs, validationError(md, path, path, r.name, msg))
+			}
+		}
+	}
+
+	if rv.CanAddr() {
+		if hook, ok := rv.Addr().Interface().(validator); ok {
+			hookErrors(hook.Validate(), prefix, md, errs)
+		}
+	}
+
+	return nil
+}
+
+// hookErrors collects the error of the Validate method of the struct at prefix
+func hookErrors(err error, prefix string, md *Metadata, errs *ValidationErrors) {
+	switch err := err.(type) {
+	case nil:
+	case ValidationErrors:
+		for _, e := range err {
+			hookErrors(e, prefix, md, errs)
+		}
+	case *ValidationError:
+		path := joinPath(prefix, err.Path)
+
+		if len(err.Path) == 0 {
+			path = prefix
+		}
+
+		rule := err.Rule
+
+		if len(rule) == 0 {
+			rule = "Validate"
+		}
+
+		*errs = append(*errs, validationError(md, path, path, rule, err.Msg))
+	default:
+		*errs = append(*errs, validationError(md, prefix, prefix, "Validate", err.Error()))
+	}
+}
+
+// validationError returns the error for the value at path, positioned at key
+func validationError(md *Metadata, key string, path string, rule string, msg string) *ValidationError {
+	err := &ValidationError{Path: path, Rule: rule, Msg: msg}
+
+	if md != nil {
+		err.Pos, _ = md.Position(key)
+		err.Source = md.Source(key)
+	}
+
+	return err
+}
+
+// validationMetadata returns new metadata when v has validate tags, so
+// validation errors have positions, nil otherwise
+func validationMetadata(v interface{}) *Metadata {
+	rv, err := decodeTarget(v)
+	if err != nil || !planOf(rv.Type()).validated {
+		return nil
+	}
+
+	return newMetadata()
+}
+
+// validate runs Validate when v has validate tags
+func validate(v interface{}, md *Metadata) error {
+	rv, err := decodeTarget(v)
+	if err != nil || !planOf(rv.Type()).validated {
+		return nil
+	}
+
+	return Validate(v, md)
+}
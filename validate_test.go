@@ -0,0 +1,280 @@
+package cfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	type target struct {
+		Name      string   `cfg:"name" validate:"nonempty,max=8"`
+		Port      int      `cfg:"port" validate:"port"`
+		Players   uint     `cfg:"players" validate:"min=1,max=4096"`
+		Ratio     float64  `cfg:"ratio" validate:"min=0.5"`
+		Mode      string   `cfg:"mode" validate:"oneof=race freeroam"`
+		Language  string   `cfg:"language" validate:"len=2"`
+		Token     string   `cfg:"token" validate:"regex=^[a-z]{2,4}$"`
+		CDNUrl    string   `cfg:"cdnUrl" validate:"url"`
+		Modules   []string `cfg:"modules" validate:"nonempty"`
+		Resources []string `cfg:"resources" validate:"regex=^[a-z]+$"`
+		Ports     []int    `cfg:"ports" validate:"port"`
+		Voice     struct {
+			Host       string `cfg:"externalHost" validate:"ip"`
+			PublicHost string `cfg:"externalPublicHost" validate:"hostname"`
+		} `cfg:"voice"`
+	}
+
+	data := []byte(`name: "a server name"
+port: 70000
+players: 0
+ratio: 0.25
+mode: sandbox
+language: eng
+token: ABC
+cdnUrl: cdn.example.com
+resources: [chat, Race, "2"]
+ports: [80, 0]
+voice: {
+  externalHost: localhost
+  externalPublicHost: -host.example.com
+}`)
+
+	var v target
+
+	err := Unmarshal(data, &v)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		"invalid name on line 1, must be at most 8 characters, got 13",
+		"invalid port on line 2, 70000 is not a valid port",
+		"invalid players on line 3, must be at least 1, got 0",
+		"invalid ratio on line 4, must be at least 0.5, got 0.25",
+		`invalid mode on line 5, "sandbox" is not one of race, freeroam`,
+		"invalid language on line 6, must have 2 characters, got 3",
+		`invalid token on line 7, "ABC" does not match ^[a-z]{2,4}$`,
+		`invalid cdnUrl on line 8, "cdn.example.com" is not a valid URL`,
+		"invalid modules, must not be empty",
+		`invalid resources[1] on line 9, "Race" does not match ^[a-z]+$`,
+		`invalid resources[2] on line 9, "2" does not match ^[a-z]+$`,
+		"invalid ports[1] on line 10, 0 is not a valid port",
+		`invalid voice.externalHost on line 12, "localhost" is not a valid IP address`,
+		`invalid voice.externalPublicHost on line 13, "-host.example.com" is not a valid hostname`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], err.Error())
+		}
+	}
+
+	if errs[1].Path != "port" || errs[1].Rule != "port" || errs[1].Pos != (Position{Line: 2, Column: 1}) {
+		t.Fatalf("wrong error fields, got %+v", errs[1])
+	}
+
+	valid := []byte(`name: server
+port: 7788
+players: 128
+ratio: 1
+mode: race
+language: en
+cdnUrl: 'https://cdn.example.com'
+modules: [js]
+resources: [chat]
+ports: [80]
+voice: {
+  externalHost: 127.0.0.1
+  externalPublicHost: voice.example.com
+}`)
+
+	err = Unmarshal(valid, &target{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	type target struct {
+		Port int `cfg:"port" validate:"port"`
+	}
+
+	fs := MapFS{
+		"server.cfg": []byte("include: local.cfg"),
+		"local.cfg":  []byte("\nport: 0"),
+	}
+
+	err := LoadFileFS(fs, "server.cfg", &target{})
+	if err == nil || err.Error() != "local.cfg: invalid port on line 2, 0 is not a valid port" {
+		t.Fatalf("expected the error on local.cfg, got %v", err)
+	}
+
+	// errors of the Validate method of the root struct have no key
+	fs["server.cfg"] = []byte("include: local.cfg\nvoice: { externalPort: 1 }")
+
+	err = LoadFileFS(fs, "server.cfg", &hookConfig{})
+	if err == nil || err.Error() != "server.cfg: invalid config, port is required" {
+		t.Fatalf("expected the error on server.cfg, got %v", err)
+	}
+
+	err = Load(&target{}, LoadOptions{Lookup: func(string) (string, bool) { return "", false }})
+	if err == nil || err.Error() != "invalid port, 0 is not a valid port" {
+		t.Fatalf("expected the error without position, got %v", err)
+	}
+}
+
+func TestValidateInvalidTags(t *testing.T) {
+	cases := []struct {
+		target interface{}
+		err    string
+	}{
+		{&struct {
+			A int `validate:"min=a"`
+		}{}, `invalid validate tag on field A, invalid rule min, "a" is not a number`},
+		{&struct {
+			A int `validate:"url"`
+		}{}, "invalid validate tag on field A, invalid rule url, only applies to strings"},
+		{&struct {
+			A string `validate:"unknown"`
+		}{}, "invalid validate tag on field A, invalid rule unknown, unknown rule"},
+		{&struct {
+			A string `validate:"regex=["`
+		}{}, "invalid validate tag on field A, invalid rule regex"},
+		{&struct {
+			Inner struct {
+				A map[string]string `validate:"nonempty"`
+			}
+		}{}, "invalid validate tag on field A, invalid rule nonempty, map[string]string is not supported"},
+	}
+
+	for _, c := range cases {
+		err := Validate(c.target, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("expected %q, got %v", c.err, err)
+		}
+	}
+}
+
+func TestIsHostname(t *testing.T) {
+	for host, valid := range map[string]bool{
+		"localhost":                      true,
+		"voice.example.com":              true,
+		"voice.example.com.":             true,
+		"94.19.213.159":                  true,
+		"a-b.c":                          true,
+		"":                               false,
+		"a..b":                           false,
+		"a_b":                            false,
+		"-a":                             false,
+		"a-":                             false,
+		strings.Repeat("a", 64) + ".com": false,
+	} {
+		if isHostname(host) != valid {
+			t.Errorf("expected isHostname(%q) to be %v", host, valid)
+		}
+	}
+}
+
+type hookVoice struct {
+	ExternalPort int `cfg:"externalPort"`
+}
+
+func (v hookVoice) Validate() error {
+	if v.ExternalPort == 0 {
+		return errors.New("externalPort is required")
+	}
+
+	return nil
+}
+
+type hookConfig struct {
+	Port     int       `cfg:"port"`
+	Announce bool      `cfg:"announce"`
+	Token    string    `cfg:"token" validate:"required_if=announce yes"`
+	UseCDN   bool      `cfg:"useCdn"`
+	CDNUrl   string    `cfg:"cdnUrl" validate:"required_if=useCdn true,url"`
+	Mode     string    `cfg:"mode"`
+	Maps     []string  `cfg:"maps" validate:"required_if=mode race"`
+	Voice    hookVoice `cfg:"voice"`
+}
+
+func (c *hookConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Voice.ExternalPort == c.Port {
+		errs = append(errs, &ValidationError{Path: "voice.externalPort", Msg: "must differ from port"})
+	}
+
+	if c.Port == 0 {
+		errs = append(errs, &ValidationError{Msg: "port is required"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func TestValidateConditional(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected []string
+	}{
+		{
+			data:     "port: 7788\nvoice: { externalPort: 7798 }",
+			expected: nil,
+		},
+		{
+			data: "port: 7788\nannounce: true\nuseCdn: true\nmode: race\nvoice: {\n  externalPort: 7788\n}",
+			expected: []string{
+				"invalid token, must be set when announce is yes",
+				"invalid cdnUrl, must be set when useCdn is true",
+				"invalid maps, must be set when mode is race",
+				"invalid voice.externalPort on line 6, must differ from port",
+			},
+		},
+		{
+			data: "announce: true\ntoken: abc\nuseCdn: true\ncdnUrl: cdn\nvoice: {\n}",
+			expected: []string{
+				`invalid cdnUrl on line 4, "cdn" is not a valid URL`,
+				"invalid voice on line 5, externalPort is required",
+				"invalid voice.externalPort, must differ from port",
+				"invalid config, port is required",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := Unmarshal([]byte(c.data), &hookConfig{})
+
+		var got []string
+
+		if errs, ok := err.(ValidationErrors); ok {
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("expected %q, got %q", c.expected, got)
+		}
+	}
+
+	err := Validate(&struct {
+		A string `validate:"required_if=b true"`
+	}{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "field with key b not found") {
+		t.Fatalf("expected error for unknown key, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package cfg
+
+import (
+	"context"
+	"crypto/sha256"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fileState is the state of a file read while loading
+type fileState struct {
+	exists bool
+	sum    [sha256.Size]byte
+}
+
+// recordingFS records the files read and the globs expanded, so the
+// watcher knows when to load again
+type recordingFS struct {
+	fs    FS
+	files map[string]fileState
+	globs map[string][]string
+}
+
+func newRecordingFS(fs FS) *recordingFS {
+	return &recordingFS{
+		fs:    fs,
+		files: make(map[string]fileState),
+		globs: make(map[string][]string),
+	}
+}
+
+func (r *recordingFS) ReadFile(name string) ([]byte, error) {
+	data, err := r.fs.ReadFile(name)
+	if err != nil {
+		r.files[name] = fileState{}
+		return nil, err
+	}
+
+	r.files[name] = fileState{exists: true, sum: sha256.Sum256(data)}
+	return data, nil
+}
+
+func (r *recordingFS) Glob(pattern string) ([]string, error) {
+	matches, err := r.fs.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	r.globs[pattern] = matches
+	return matches, nil
+}
+
+// changed reports whether any file or glob recorded is different on fs
+func (r *recordingFS) changed(fs FS) bool {
+	for name, state := range r.files {
+		data, err := fs.ReadFile(name)
+
+		if err != nil {
+			if state.exists {
+				return true
+			}
+
+			continue
+		}
+
+		if !state.exists || sha256.Sum256(data) != state.sum {
+			return true
+		}
+	}
+
+	for pattern, matches := range r.globs {
+		current, err := fs.Glob(pattern)
+		if err != nil || !reflect.DeepEqual(current, matches) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatcherOptions configures a Watcher
+type WatcherOptions struct {
+	// New returns a pointer to a new struct to decode into, it can hold the
+	// defaults. Every reload decodes into a new value, values are never modified
+	New func() interface{}
+	// Validate checks a decoded value before it replaces the current one,
+	// after the validate tags, optional
+	Validate func(v interface{}) error
+	// OnError is called when a reload fails, the current value is kept, optional
+	OnError func(err error)
+	// Interval between checks of the files, one second when zero
+	Interval time.Duration
+	// FS is the file system the files are read from, OSFS when nil
+	FS FS
+}
+
+// Subscriber is notified when the config changes with the previous
+// value, the new value and the fields that changed
+type Subscriber func(old interface{}, new interface{}, changes []Change)
+
+// Watcher keeps a config loaded from a file, and the files it includes,
+// up to date. When a file changes the config is decoded into a new value
+// and validated, only then it replaces the current value
+type Watcher struct {
+	path string
+	opts WatcherOptions
+
+	current atomic.Value
+
+	// mu serializes reloads and guards recorded and subscribers
+	mu          sync.Mutex
+	recorded    *recordingFS
+	subscribers []Subscriber
+}
+
+// NewWatcher loads the config file at path, failing when the first load
+// or validation fails. Call Run to start watching the files
+func NewWatcher(path string, opts WatcherOptions) (*Watcher, error) {
+	if opts.New == nil {
+		return nil, errors.New("watcher needs a New function")
+	}
+
+	if opts.FS == nil {
+		opts.FS = OSFS{}
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	w := &Watcher{path: path, opts: opts}
+
+	v, recorded, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	w.recorded = recorded
+	w.current.Store(v)
+	return w, nil
+}
+
+// Current returns the current config, it is safe for concurrent use.
+// The value must not be modified
+func (w *Watcher) Current() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe adds fn to the functions called after the config changes.
+// Subscribers are called in order, from the goroutine doing the reload,
+// after the reload finished so they can call Subscribe and Reload
+func (w *Watcher) Subscribe(fn Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Reload loads the files again, even when they did not change. On errors
+// the current config is kept, the error is returned and passed to OnError
+func (w *Watcher) Reload() error {
+	_, err := w.reloadChanges()
+	return err
+}
+
+// reloadChanges is Reload returning the changes of the new config
+func (w *Watcher) reloadChanges() ([]Change, error) {
+	w.mu.Lock()
+	notify, changes, err := w.reload()
+	w.mu.Unlock()
+
+	notify()
+	return changes, err
+}
+
+// Run checks the files on every interval, reloading the config when they
+// change, until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll reloads the config when the files changed
+func (w *Watcher) poll() {
+	w.mu.Lock()
+
+	if !w.recorded.changed(w.opts.FS) {
+		w.mu.Unlock()
+		return
+	}
+
+	notify, _, _ := w.reload()
+	w.mu.Unlock()
+
+	notify()
+}
+
+// reload loads the config, it must be called with mu held. The returned
+// notify calls OnError or the subscribers, it must be called after mu is
+// released so they can call the methods of the watcher
+func (w *Watcher) reload() (notify func(), changes []Change, err error) {
+	v, recorded, err := w.load()
+
+	// the files are recorded even on errors, so a broken edit is
+	// reported once and the next edit is noticed
+	w.recorded = recorded
+
+	if err != nil {
+		notify = func() {
+			if w.opts.OnError != nil {
+				w.opts.OnError(err)
+			}
+		}
+
+		return notify, nil, err
+	}
+
+	old := w.current.Load()
+	w.current.Store(v)
+
+	// both values come from New, so they have the same type
+	changes, _ = DiffStructs(old, v)
+	if len(changes) == 0 {
+		return func() {}, nil, nil
+	}
+
+	subscribers := append([]Subscriber(nil), w.subscribers...)
+
+	notify = func() {
+		for _, fn := range subscribers {
+			fn(old, v, changes)
+		}
+	}
+
+	return notify, changes, nil
+}
+
+func (w *Watcher) load() (interface{}, *recordingFS, error) {
+	recorded := newRecordingFS(w.opts.FS)
+	v := w.opts.New()
+
+	// arrays in the file replace the defaults returned by New
+	d := decodeState{md: validationMetadata(v), merge: true}
+
+	err := newLoader(recorded, d).loadFiles(v, w.path)
+	if err != nil {
+		return nil, recorded, err
+	}
+
+	err = validate(v, d.md)
+	if err != nil {
+		return nil, recorded, err
+	}
+
+	if w.opts.Validate != nil {
+		err = w.opts.Validate(v)
+		if err != nil {
+			return nil, recorded, &FileError{Chain: []string{w.path}, Err: err}
+		}
+	}
+
+	return v, recorded, nil
+}
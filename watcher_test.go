@@ -0,0 +1,271 @@
+package cfg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type watcherConfig struct {
+	Name  string `cfg:"name"`
+	Port  int    `cfg:"port"`
+	Voice struct {
+		BitRate int `cfg:"bitrate"`
+	} `cfg:"voice"`
+	Resources []string `cfg:"resources,merge=append"`
+}
+
+func newWatcherConfig() interface{} {
+	return &watcherConfig{Port: 7788}
+}
+
+func TestWatcher(t *testing.T) {
+	fs := MapFS{
+		"server.cfg":    []byte("name: server\ninclude: conf.d/*.cfg\nvoice: {\n  bitrate: 64000\n}"),
+		"conf.d/a.cfg":  []byte("resources: [chat]"),
+		"resources.cfg": []byte("resources: [race]"),
+	}
+
+	var errs []error
+
+	w, err := NewWatcher("server.cfg", WatcherOptions{
+		FS:  fs,
+		New: newWatcherConfig,
+		Validate: func(v interface{}) error {
+			if v.(*watcherConfig).Port == 0 {
+				return errors.New("port is required")
+			}
+
+			return nil
+		},
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := w.Current().(*watcherConfig)
+
+	if first.Name != "server" || first.Port != 7788 || !reflect.DeepEqual(first.Resources, []string{"chat"}) {
+		t.Fatalf("wrong initial config, got %+v", first)
+	}
+
+	var calls int
+	var changes []Change
+
+	w.Subscribe(func(old interface{}, new interface{}, c []Change) {
+		calls++
+		changes = c
+
+		if old != first || new != w.Current() {
+			t.Fatalf("subscriber got wrong values")
+		}
+	})
+
+	w.poll()
+
+	if calls != 0 || w.Current() != first {
+		t.Fatalf("expected no reload without changes")
+	}
+
+	t.Run("changed file", func(t *testing.T) {
+		fs["server.cfg"] = []byte("name: server\ninclude: conf.d/*.cfg\nvoice: {\n  bitrate: 128000\n}")
+		w.poll()
+
+		if calls != 1 {
+			t.Fatalf("expected one notification, got %d", calls)
+		}
+
+		expected := []Change{{Path: "voice.bitrate", Kind: Modified, Old: 64000, New: 128000}}
+
+		if !reflect.DeepEqual(changes, expected) {
+			t.Fatalf("expected %v, got %v", expected, changes)
+		}
+	})
+
+	t.Run("new file matching glob", func(t *testing.T) {
+		first = w.Current().(*watcherConfig)
+		fs["conf.d/b.cfg"] = []byte("resources: [race]")
+		w.poll()
+
+		if calls != 2 || !reflect.DeepEqual(w.Current().(*watcherConfig).Resources, []string{"chat", "race"}) {
+			t.Fatalf("expected the new include to be loaded, got %+v", w.Current())
+		}
+	})
+
+	t.Run("broken edit keeps the last good config", func(t *testing.T) {
+		current := w.Current()
+		fs["conf.d/a.cfg"] = []byte("resources: [chat")
+		w.poll()
+		w.poll()
+
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "conf.d/a.cfg") {
+			t.Fatalf("expected one error for conf.d/a.cfg, got %v", errs)
+		}
+
+		if w.Current() != current || calls != 2 {
+			t.Fatalf("expected the last good config to be kept")
+		}
+	})
+
+	t.Run("invalid config keeps the last good config", func(t *testing.T) {
+		current := w.Current()
+		fs["conf.d/a.cfg"] = []byte("resources: [chat]\nport: 0")
+		w.poll()
+
+		if len(errs) != 2 || !strings.Contains(errs[1].Error(), "port is required") {
+			t.Fatalf("expected a validation error, got %v", errs)
+		}
+
+		if w.Current() != current {
+			t.Fatalf("expected the last good config to be kept")
+		}
+	})
+
+	t.Run("fixed edit", func(t *testing.T) {
+		first = w.Current().(*watcherConfig)
+		fs["conf.d/a.cfg"] = []byte("resources: [chat]\nport: 80")
+		w.poll()
+
+		if calls != 3 || w.Current().(*watcherConfig).Port != 80 {
+			t.Fatalf("expected the fixed config to be loaded, got %+v", w.Current())
+		}
+	})
+}
+
+func TestNewWatcherErrors(t *testing.T) {
+	_, err := NewWatcher("server.cfg", WatcherOptions{FS: MapFS{}, New: newWatcherConfig})
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	_, err = NewWatcher("server.cfg", WatcherOptions{FS: MapFS{"server.cfg": nil}})
+	if err == nil {
+		t.Fatal("expected error without New")
+	}
+}
+
+func TestWatcherReplacesDefaultSlices(t *testing.T) {
+	type config struct {
+		Resources []string `cfg:"resources"`
+	}
+
+	w, err := NewWatcher("server.cfg", WatcherOptions{
+		FS: MapFS{"server.cfg": []byte("resources: [a, b]")},
+		New: func() interface{} {
+			return &config{Resources: []string{"default"}}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := w.Current().(*config).Resources; !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected the file to replace the defaults, got %v", got)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	fs := MapFS{"server.cfg": []byte("port: 80")}
+
+	w, err := NewWatcher("server.cfg", WatcherOptions{FS: fs, New: newWatcherConfig, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan []Change, 1)
+
+	w.Subscribe(func(old interface{}, new interface{}, changes []Change) {
+		changed <- changes
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	w.Reload()
+
+	select {
+	case <-changed:
+		t.Fatal("expected no notification without changes")
+	default:
+	}
+
+	// MapFS is not safe for concurrent use, the change goes through a reload lock
+	w.mu.Lock()
+	fs["server.cfg"] = []byte("port: 81")
+	w.mu.Unlock()
+
+	select {
+	case changes := <-changed:
+		if len(changes) != 1 || changes[0].Path != "port" {
+			t.Fatalf("wrong changes, got %v", changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a reload")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWatcherReloadFromSubscriber(t *testing.T) {
+	fs := MapFS{"server.cfg": []byte("port: 80")}
+
+	var errs []error
+
+	w, err := NewWatcher("server.cfg", WatcherOptions{
+		FS:  fs,
+		New: newWatcherConfig,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+
+	w.Subscribe(func(old interface{}, new interface{}, changes []Change) {
+		calls++
+
+		// breaks the file, the reload from the subscriber reports it
+		fs["server.cfg"] = []byte("port: [")
+
+		if err := w.Reload(); err == nil {
+			t.Error("expected the reload from the subscriber to fail")
+		}
+
+		w.Subscribe(func(old interface{}, new interface{}, changes []Change) {})
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		fs["server.cfg"] = []byte("port: 81")
+		done <- w.Reload()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload from a subscriber deadlocked")
+	}
+
+	if calls != 1 || len(errs) != 1 || w.Current().(*watcherConfig).Port != 81 {
+		t.Fatalf("expected one notification and one error, got %d %v %+v", calls, errs, w.Current())
+	}
+}